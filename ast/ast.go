@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"github.com/aryuuu/gonkey-lang/token"
@@ -10,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position just after the last character of the node
 }
 
 type Statement interface {
@@ -34,6 +37,22 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -48,12 +67,27 @@ type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
 	Value Expression
+	Doc   string // text of the /// comments before the statement, if any
 }
 
 func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Span.Start
+}
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+
+	return ls.Token.Span.End
+}
 
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
@@ -74,12 +108,19 @@ func (ls *LetStatement) String() string {
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	EndToken   token.Token // the } token
 }
 
 func (bs *BlockStatement) statementNode() {}
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Span.Start
+}
+func (bs *BlockStatement) End() token.Position {
+	return bs.EndToken.Span.End
+}
 func (bs *BlockStatement) String() string {
 	var result bytes.Buffer
 
@@ -101,6 +142,14 @@ func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Span.Start
+}
+
+func (i *Identifier) End() token.Position {
+	return i.Token.Span.End
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -115,6 +164,18 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+func (rs *ReturnStatement) Pos() token.Position {
+	return rs.Token.Span.Start
+}
+
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+
+	return rs.Token.Span.End
+}
+
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -129,6 +190,109 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+func (ws *WhileStatement) Pos() token.Position {
+	return ws.Token.Span.Start
+}
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+
+	return ws.Token.Span.End
+}
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+// ForStatement runs Body once for every element of Iterable, binding the
+// element to Variable. Like WhileStatement it evaluates to null.
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+func (fs *ForStatement) Pos() token.Position {
+	return fs.Token.Span.Start
+}
+func (fs *ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+
+	return fs.Token.Span.End
+}
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for(")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Span.Start
+}
+func (bs *BreakStatement) End() token.Position {
+	return bs.Token.Span.End
+}
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Span.Start
+}
+func (cs *ContinueStatement) End() token.Position {
+	return cs.Token.Span.End
+}
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
 	Expression Expression
@@ -139,6 +303,18 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Span.Start
+}
+
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+
+	return es.Token.Span.End
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -150,16 +326,42 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // set instead of Value when the literal exceeds int64
 }
 
 func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
+func (il *IntegerLiteral) Pos() token.Position {
+	return il.Token.Span.Start
+}
+func (il *IntegerLiteral) End() token.Position {
+	return il.Token.Span.End
+}
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+func (fl *FloatLiteral) Pos() token.Position {
+	return fl.Token.Span.Start
+}
+func (fl *FloatLiteral) End() token.Position {
+	return fl.Token.Span.End
+}
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -169,19 +371,67 @@ func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Span.Start
+}
+func (sl *StringLiteral) End() token.Position {
+	return sl.Token.Span.End
+}
 func (sl *StringLiteral) String() string {
 	return sl.Token.Literal
 }
 
+// InterpolatedString is a string with embedded ${...} expressions, its parts
+// are the StringLiterals between them and the expressions themselves.
+type InterpolatedString struct {
+	Token    token.Token // the STRING_HEAD token
+	Parts    []Expression
+	EndToken token.Token // the STRING_TAIL token
+}
+
+func (is *InterpolatedString) expressionNode() {}
+func (is *InterpolatedString) TokenLiteral() string {
+	return is.Token.Literal
+}
+func (is *InterpolatedString) Pos() token.Position {
+	return is.Token.Span.Start
+}
+func (is *InterpolatedString) End() token.Position {
+	return is.EndToken.Span.End
+}
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if literal, ok := part.(*StringLiteral); ok {
+			out.WriteString(literal.Value)
+			continue
+		}
+
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
-	Token    token.Token
+	Token    token.Token // the [ token
 	Elements []Expression
+	EndToken token.Token // the ] token
 }
 
 func (al *ArrayLiteral) expressionNode() {}
 func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Span.Start
+}
+func (al *ArrayLiteral) End() token.Position {
+	return al.EndToken.Span.End
+}
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -197,21 +447,98 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// TupleLiteral is (a, b), a single element needs a trailing comma, (a,), to
+// tell it from a grouped expression.
+type TupleLiteral struct {
+	Token    token.Token // the ( token
+	Elements []Expression
+	EndToken token.Token // the ) token
+}
+
+func (tl *TupleLiteral) expressionNode() {}
+func (tl *TupleLiteral) TokenLiteral() string {
+	return tl.Token.Literal
+}
+func (tl *TupleLiteral) Pos() token.Position {
+	return tl.Token.Span.Start
+}
+func (tl *TupleLiteral) End() token.Position {
+	return tl.EndToken.Span.End
+}
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// SetLiteral is {a, b}, it is told from a map literal by the missing colon
+// after the first element. There is no empty set literal, {} is a map.
+type SetLiteral struct {
+	Token    token.Token // the { token
+	Elements []Expression
+	EndToken token.Token // the } token
+}
+
+func (sl *SetLiteral) expressionNode() {}
+func (sl *SetLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+func (sl *SetLiteral) Pos() token.Position {
+	return sl.Token.Span.Start
+}
+func (sl *SetLiteral) End() token.Position {
+	return sl.EndToken.Span.End
+}
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type MapLiteral struct {
-	Token token.Token
-	Pairs map[Expression]Expression
+	Token    token.Token // the { token
+	Pairs    map[Expression]Expression
+	Keys     []Expression // the keys of Pairs in source order
+	EndToken token.Token  // the } token
 }
 
 func (ml *MapLiteral) expressionNode() {}
 func (ml *MapLiteral) TokenLiteral() string {
 	return ml.Token.Literal
 }
+func (ml *MapLiteral) Pos() token.Position {
+	return ml.Token.Span.Start
+}
+func (ml *MapLiteral) End() token.Position {
+	return ml.EndToken.Span.End
+}
 func (ml *MapLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range ml.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range ml.Keys {
+		pairs = append(pairs, key.String()+":"+ml.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -231,6 +558,16 @@ func (fl *FunctionLiteral) expressionNode() {}
 func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Span.Start
+}
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+
+	return fl.Token.Span.End
+}
 func (fl *FunctionLiteral) String() string {
 	var result bytes.Buffer
 
@@ -258,6 +595,16 @@ func (pe *PrefixExpression) expressionNode() {}
 func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
+func (pe *PrefixExpression) Pos() token.Position {
+	return pe.Token.Span.Start
+}
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+
+	return pe.Token.Span.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -280,6 +627,20 @@ func (ie *InfixExpression) expressionNode() {}
 func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+
+	return ie.Token.Span.Start
+}
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+
+	return ie.Token.Span.End
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -303,6 +664,20 @@ func (ie *IfExpression) expressionNode() {}
 func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Span.Start
+}
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+
+	return ie.Token.Span.End
+}
 func (ie *IfExpression) String() string {
 	var result bytes.Buffer
 
@@ -320,15 +695,26 @@ func (ie *IfExpression) String() string {
 }
 
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // the ( token
 	Function  Expression
 	Arguments []Expression
+	EndToken  token.Token // the ) token
 }
 
 func (ce *CallExpression) expressionNode() {}
 func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+
+	return ce.Token.Span.Start
+}
+func (ce *CallExpression) End() token.Position {
+	return ce.EndToken.Span.End
+}
 func (ce *CallExpression) String() string {
 	var result bytes.Buffer
 
@@ -354,28 +740,182 @@ func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Span.Start
+}
+func (b *Boolean) End() token.Position {
+	return b.Token.Span.End
+}
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
-type IndexExpression struct {
+type NullLiteral struct {
 	Token token.Token
-	Left  Expression
-	Index Expression
+}
+
+func (nl *NullLiteral) expressionNode() {}
+func (nl *NullLiteral) TokenLiteral() string {
+	return nl.Token.Literal
+}
+func (nl *NullLiteral) Pos() token.Position {
+	return nl.Token.Span.Start
+}
+func (nl *NullLiteral) End() token.Position {
+	return nl.Token.Span.End
+}
+func (nl *NullLiteral) String() string {
+	return nl.Token.Literal
+}
+
+// IndexExpression is Left[Index], or Left?[Index] when Optional. a?.b is
+// parsed as a?["b"].
+type IndexExpression struct {
+	Token    token.Token // the [, ?[ or ?. token
+	Left     Expression
+	Index    Expression
+	Optional bool
+	EndToken token.Token // the ] token, or the name after ?.
 }
 
 func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+
+	return ie.Token.Span.Start
+}
+func (ie *IndexExpression) End() token.Position {
+	return ie.EndToken.Span.End
+}
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 
 	return out.String()
 }
+
+// SliceExpression is Left[Low:High], or Left?[Low:High] when Optional.
+// Either bound is nil when it is left out.
+type SliceExpression struct {
+	Token    token.Token // the [ or ?[ token
+	Left     Expression
+	Low      Expression
+	High     Expression
+	Optional bool
+	EndToken token.Token // the ] token
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+func (se *SliceExpression) Pos() token.Position {
+	if se.Left != nil {
+		return se.Left.Pos()
+	}
+
+	return se.Token.Span.Start
+}
+func (se *SliceExpression) End() token.Position {
+	return se.EndToken.Span.End
+}
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// GroupedExpression is a chain of calls, indexes and slices in parentheses.
+// The parentheses end the chain, an optional link inside them does not skip
+// the links that follow. Other expressions are not kept in a group.
+type GroupedExpression struct {
+	Token      token.Token // the ( token
+	Expression Expression
+	EndToken   token.Token // the ) token
+}
+
+func (ge *GroupedExpression) expressionNode() {}
+func (ge *GroupedExpression) TokenLiteral() string {
+	return ge.Token.Literal
+}
+func (ge *GroupedExpression) Pos() token.Position {
+	return ge.Token.Span.Start
+}
+func (ge *GroupedExpression) End() token.Position {
+	return ge.EndToken.Span.End
+}
+func (ge *GroupedExpression) String() string {
+	return ge.Expression.String()
+}
+
+// AssignExpression stores Value into Target, which is either an *Identifier
+// or an *IndexExpression.
+type AssignExpression struct {
+	Token  token.Token // the = token
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+
+	return ae.Token.Span.Start
+}
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+
+	return ae.Token.Span.End
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+
+	return out.String()
+}
+
+// SpanOf returns the source span covered by the given node.
+func SpanOf(n Node) token.Span {
+	return token.Span{
+		Start: n.Pos(),
+		End:   n.End(),
+	}
+}
@@ -0,0 +1,133 @@
+package code
+
+import (
+	"testing"
+
+	"github.com/aryuuu/gonkey-lang/token"
+)
+
+func TestMake(t *testing.T) {
+	testCases := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, tc := range testCases {
+		instruction := Make(tc.op, tc.operands...)
+
+		if len(instruction) != len(tc.expected) {
+			t.Errorf("instruction has wrong length. expected=%d, got=%d", len(tc.expected), len(instruction))
+		}
+
+		for i, b := range tc.expected {
+			if instruction[i] != tc.expected[i] {
+				t.Errorf("wrong byte at pos %d. expected=%d, got=%d", i, b, instruction[i])
+			}
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nexpected=%q\ngot=%q", expected, concatted.String())
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	testCases := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
+	}
+
+	for _, tc := range testCases {
+		instruction := Make(tc.op, tc.operands...)
+
+		def, err := Lookup(byte(tc.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q", err)
+		}
+
+		operandsRead, n := ReadOperands(def, instruction[1:])
+		if n != tc.bytesRead {
+			t.Fatalf("n wrong. expected=%d, got=%d", tc.bytesRead, n)
+		}
+
+		for i, expected := range tc.operands {
+			if operandsRead[i] != expected {
+				t.Errorf("operand wrong. expected=%d, got=%d", expected, operandsRead[i])
+			}
+		}
+	}
+}
+
+func TestPosTable(t *testing.T) {
+	at := func(line int) token.Position {
+		return token.Position{Line: line, Column: 1}
+	}
+
+	var table PosTable
+	table = table.Add(0, at(1))
+	table = table.Add(3, at(1))
+	table = table.Add(4, at(2))
+	table = table.Add(7, at(3))
+	// the instruction at 7 is removed and another one takes its place
+	table = table.Add(7, at(4))
+
+	if len(table) != 3 {
+		t.Fatalf("wrong number of entries. expected=3, got=%d", len(table))
+	}
+
+	testCases := []struct {
+		offset   int
+		expected string
+	}{
+		{0, "1:1"},
+		{3, "1:1"},
+		{4, "2:1"},
+		{6, "2:1"},
+		{7, "4:1"},
+		{100, "4:1"},
+	}
+
+	for _, tc := range testCases {
+		pos := table.Lookup(tc.offset)
+		if pos.String() != tc.expected {
+			t.Errorf("wrong position at %d. expected=%s, got=%s", tc.offset, tc.expected, pos)
+		}
+	}
+
+	if pos := (PosTable{}).Lookup(0); pos.IsValid() {
+		t.Errorf("empty table should have no positions, got=%s", pos)
+	}
+}
@@ -0,0 +1,841 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/aryuuu/gonkey-lang/ast"
+	"github.com/aryuuu/gonkey-lang/code"
+	"github.com/aryuuu/gonkey-lang/evaluator"
+	"github.com/aryuuu/gonkey-lang/object"
+	"github.com/aryuuu/gonkey-lang/token"
+)
+
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	// err is the first operand found not to fit in its instruction, it is
+	// returned once the node being compiled is done
+	err error
+
+	// positions of the nodes being compiled, innermost last, instructions
+	// are attributed to the innermost one
+	nodePositions []token.Position
+}
+
+// Error is a compile error, located at the node that could not be compiled.
+type Error struct {
+	Message string
+	Pos     token.Position
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+type CompilationScope struct {
+	instructions        code.Instructions
+	positions           code.PosTable
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+
+	loops []*Loop
+}
+
+// Loop tracks the jumps of a loop while its body is being compiled.
+type Loop struct {
+	start      int   // where continue jumps to
+	breakJumps []int // jumps emitted by break, patched to the loop's exit
+}
+
+type Bytecode struct {
+	Instructions code.Instructions
+	Positions    code.PosTable
+	Constants    []object.Object
+	GlobalNames  []string // indexed like the globals, to report unset ones
+}
+
+func New() *Compiler {
+	mainScope := CompilationScope{
+		instructions: code.Instructions{},
+	}
+
+	symbolTable := NewSymbolTable()
+	for idx, name := range evaluator.BuiltinNames() {
+		symbolTable.DefineBuiltin(idx, name)
+	}
+
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// NewWithState creates a compiler that keeps defining into an existing
+// symbol table and constant pool, e.g. across several REPL inputs.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	compiler := New()
+	compiler.symbolTable = s
+	compiler.constants = constants
+
+	return compiler
+}
+
+func (c *Compiler) Compile(node ast.Node) (err error) {
+	c.nodePositions = append(c.nodePositions, node.Pos())
+	defer func() {
+		c.nodePositions = c.nodePositions[:len(c.nodePositions)-1]
+
+		if _, ok := err.(*Error); err != nil && !ok {
+			err = &Error{Message: err.Error(), Pos: node.Pos()}
+		}
+	}()
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		// the name is defined once its value is compiled, the value still
+		// sees what the name meant before, like in the evaluator. Function
+		// literals are the exception so they can call themselves.
+		if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+			symbol := c.symbolTable.Define(node.Name.Value)
+			if err := c.compileFunction(fn, node.Name.Value); err != nil {
+				return err
+			}
+			c.storeSymbol(symbol)
+			break
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.storeSymbol(c.symbolTable.Define(node.Name.Value))
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			// a global defined further down, or a name that is never
+			// defined, either way it is known once the program runs
+			symbol = c.symbolTable.DefineGlobal(node.Value)
+		}
+		c.loadSymbol(symbol)
+
+	case *ast.IntegerLiteral:
+		var integer object.Object = &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			integer = &object.BigInt{Value: node.Big}
+		}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			if err := c.Compile(part); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpInterpolate, len(node.Parts))
+
+	case *ast.TupleLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpTuple, len(node.Elements))
+
+	case *ast.SetLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSet, len(node.Elements))
+
+	case *ast.MapLiteral:
+		// pairs are compiled in source order, which is the order of the map
+		for _, k := range node.Keys {
+			if err := c.Compile(k); err != nil {
+				return err
+			}
+			if err := c.Compile(node.Pairs[k]); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpMap, len(node.Pairs)*2)
+
+	case *ast.IndexExpression, *ast.SliceExpression:
+		return c.compileChain(node.(ast.Expression))
+
+	case *ast.GroupedExpression:
+		return c.Compile(node.Expression)
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return c.compileLogical(node)
+		}
+
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		op, ok := infixOpcodes[node.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+		c.emit(op)
+
+	case *ast.IfExpression:
+		if err := c.Compile(node.Condition); err != nil {
+			return err
+		}
+
+		// bogus offset, patched once the consequence is compiled
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		if err := c.compileBlockValue(node.Consequence); err != nil {
+			return err
+		}
+
+		jumpPos := c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+		if node.Alternative == nil {
+			c.emit(code.OpNull)
+		} else if err := c.compileBlockValue(node.Alternative); err != nil {
+			return err
+		}
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	case *ast.FunctionLiteral:
+		return c.compileFunction(node, "")
+
+	case *ast.AssignExpression:
+		return c.compileAssign(node)
+
+	// loops evaluate to null, like in the evaluator
+	case *ast.WhileStatement:
+		start := len(c.currentInstructions())
+
+		if err := c.Compile(node.Condition); err != nil {
+			return err
+		}
+		exitJump := c.emit(code.OpJumpNotTruthy, 9999)
+
+		if err := c.compileLoopBody(node.Body, start); err != nil {
+			return err
+		}
+
+		c.changeOperand(exitJump, len(c.currentInstructions()))
+		c.emit(code.OpNull)
+		c.emit(code.OpPop)
+
+	case *ast.ForStatement:
+		if err := c.Compile(node.Iterable); err != nil {
+			return err
+		}
+		c.emit(code.OpIter)
+
+		symbol := c.symbolTable.Define(node.Variable.Value)
+
+		start := c.emit(code.OpIterNext, 9999)
+		c.storeSymbol(symbol)
+
+		if err := c.compileLoopBody(node.Body, start); err != nil {
+			return err
+		}
+
+		c.changeOperand(start, len(c.currentInstructions()))
+		// drop the iterator
+		c.emit(code.OpPop)
+		c.emit(code.OpNull)
+		c.emit(code.OpPop)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("break outside of a loop")
+		}
+		loop.breakJumps = append(loop.breakJumps, c.emit(code.OpJump, 9999))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("continue outside of a loop")
+		}
+		c.emit(code.OpJump, loop.start)
+
+	case *ast.CallExpression:
+		return c.compileChain(node)
+
+	default:
+		return fmt.Errorf("unsupported node %T", node)
+	}
+
+	return c.err
+}
+
+var infixOpcodes = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"|":  code.OpUnion,
+	"&":  code.OpIntersect,
+	">":  code.OpGreaterThan,
+	"<":  code.OpLessThan,
+	">=": code.OpGreaterEqual,
+	"<=": code.OpLessEqual,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+}
+
+// compileBlockValue compiles a block used as an expression, leaving the value
+// of its last expression on the stack, or null when there is none.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// compileLoopBody compiles the body of a loop starting at start, followed by
+// the jump back to it. Jumps emitted by break are patched to the instruction
+// following the loop body.
+func (c *Compiler) compileLoopBody(body *ast.BlockStatement, start int) error {
+	loop := &Loop{start: start}
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, loop)
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+	c.emit(code.OpJump, start)
+
+	scope = &c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	for _, pos := range loop.breakJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
+func (c *Compiler) currentLoop() *Loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+
+	return loops[len(loops)-1]
+}
+
+// compileChain compiles a chain of calls, indexes and slices. An optional
+// link jumps over the rest of the chain when its left side is null, which is
+// then the value of the whole chain.
+func (c *Compiler) compileChain(node ast.Expression) error {
+	var nullJumps []int
+	if err := c.compileChainLink(node, &nullJumps); err != nil {
+		return err
+	}
+
+	for _, pos := range nullJumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileChainLink(node ast.Expression, nullJumps *[]int) error {
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		if err := c.compileChainLink(node.Function, nullJumps); err != nil {
+			return err
+		}
+
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+
+	case *ast.IndexExpression:
+		if err := c.compileChainLink(node.Left, nullJumps); err != nil {
+			return err
+		}
+
+		if node.Optional {
+			// bogus offset, patched once the whole chain is compiled
+			*nullJumps = append(*nullJumps, c.emit(code.OpJumpNull, 9999))
+		}
+
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.SliceExpression:
+		if err := c.compileChainLink(node.Left, nullJumps); err != nil {
+			return err
+		}
+
+		if node.Optional {
+			*nullJumps = append(*nullJumps, c.emit(code.OpJumpNull, 9999))
+		}
+
+		for _, bound := range []ast.Expression{node.Low, node.High} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSlice)
+
+	default:
+		return c.Compile(node)
+	}
+
+	return nil
+}
+
+// compileLogical emits the right operand of && or || behind a jump, the left
+// one has already been compiled and stays on the stack when it decides the
+// result.
+func (c *Compiler) compileLogical(node *ast.InfixExpression) error {
+	op := code.OpJumpNotTruthyOrPop
+	switch node.Operator {
+	case "||":
+		op = code.OpJumpTruthyOrPop
+	case "??":
+		op = code.OpJumpNotNullOrPop
+	}
+
+	// bogus offset, patched once the right operand is compiled
+	jumpPos := c.emit(op, 9999)
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileAssign leaves the assigned value on the stack, an assignment is an
+// expression like any other.
+func (c *Compiler) compileAssign(node *ast.AssignExpression) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok {
+			// like a read, see the Identifier case, assigning to the
+			// global before it is defined fails once the program runs
+			symbol = c.symbolTable.DefineGlobal(target.Value)
+		}
+		if symbol.Scope == BuiltinScope {
+			return fmt.Errorf("assignment to undeclared identifier: %s", target.Value)
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.assignSymbol(symbol)
+		c.loadSymbol(symbol)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	for _, p := range node.Parameters {
+		c.symbolTable.Define(p.Value)
+	}
+	c.declareLets(node.Body)
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	localNames := c.symbolTable.Names(LocalScope)
+	positions := c.scopes[c.scopeIndex].positions
+	instructions := c.leaveScope()
+
+	// free variables are captured by reference, assignments on either side
+	// are seen by the other, like with the evaluator's environments
+	for _, s := range freeSymbols {
+		if s.Scope == LocalScope {
+			c.emit(code.OpCaptureLocal, s.Index)
+		} else {
+			c.emit(code.OpCaptureFree, s.Index)
+		}
+	}
+
+	freeNames := make([]string, len(freeSymbols))
+	for i, s := range freeSymbols {
+		freeNames[i] = s.Name
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Name:          name,
+		Instructions:  instructions,
+		Positions:     positions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		LocalNames:    localNames,
+		FreeNames:     freeNames,
+		Parameters:    node.Parameters,
+		Body:          node.Body,
+	}
+
+	c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+
+	return nil
+}
+
+// declareLets declares the names block binds in the current scope, including
+// those of the blocks of its loops and ifs, which share the scope.
+func (c *Compiler) declareLets(block *ast.BlockStatement) {
+	for _, s := range block.Statements {
+		switch s := s.(type) {
+		case *ast.LetStatement:
+			c.symbolTable.Declare(s.Name.Value)
+		case *ast.WhileStatement:
+			c.declareLets(s.Body)
+		case *ast.ForStatement:
+			c.symbolTable.Declare(s.Variable.Value)
+			c.declareLets(s.Body)
+		case *ast.ExpressionStatement:
+			if ie, ok := s.Expression.(*ast.IfExpression); ok {
+				c.declareLets(ie.Consequence)
+				if ie.Alternative != nil {
+					c.declareLets(ie.Alternative)
+				}
+			}
+		}
+	}
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Positions:    c.scopes[c.scopeIndex].positions,
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.Names(GlobalScope),
+	}
+}
+
+// SymbolTable returns the global symbol table, to be passed to NewWithState.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	c.checkOperands(op, operands)
+
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.positions = scope.positions.Add(pos, c.nodePositions[len(c.nodePositions)-1])
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	c.checkOperands(op, []int{operand})
+
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// operandLimits names what the operands of an instruction hold, as reported
+// when they no longer fit. Operands holding an index allow one value more
+// than operands holding a count, counting from zero.
+var operandLimits = map[code.Opcode][]struct {
+	what    string
+	isIndex bool
+}{
+	code.OpConstant:     {{"constants", true}},
+	code.OpClosure:      {{"constants", true}, {"free variables", false}},
+	code.OpGetGlobal:    {{"global variables", true}},
+	code.OpSetGlobal:    {{"global variables", true}},
+	code.OpGetLocal:     {{"local variables", true}},
+	code.OpSetLocal:     {{"local variables", true}},
+	code.OpCaptureLocal: {{"local variables", true}},
+	code.OpGetFree:      {{"free variables", true}},
+	code.OpSetFree:      {{"free variables", true}},
+	code.OpCaptureFree:  {{"free variables", true}},
+	code.OpGetBuiltin:   {{"builtins", true}},
+	code.OpCall:         {{"arguments", false}},
+	code.OpArray:        {{"elements", false}},
+	code.OpTuple:        {{"elements", false}},
+	code.OpSet:          {{"elements", false}},
+	code.OpMap:          {{"keys and values", false}},
+	code.OpInterpolate:  {{"interpolated parts", false}},
+}
+
+// checkOperands records an error when an operand does not fit in the width
+// op gives it, code.Make would silently truncate it. Jump targets are the
+// only operands left out of operandLimits, they overflow on functions with
+// too many instructions.
+func (c *Compiler) checkOperands(op code.Opcode, operands []int) {
+	def, err := code.Lookup(byte(op))
+	if err != nil || c.err != nil {
+		return
+	}
+
+	for i, operand := range operands {
+		max := 1<<(8*def.OperandWidths[i]) - 1
+		if operand <= max {
+			continue
+		}
+
+		limits, ok := operandLimits[op]
+		if !ok {
+			c.err = fmt.Errorf("too many instructions in a function, at most %d bytes are supported", max)
+			return
+		}
+
+		limit := limits[i]
+		if limit.isIndex {
+			max += 1
+		}
+		c.err = fmt.Errorf("too many %s, at most %d are supported", limit.what, max)
+		return
+	}
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions: code.Instructions{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex += 1
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex -= 1
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) assignSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpAssignGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpAssignFree, s.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpSetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
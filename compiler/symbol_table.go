@@ -0,0 +1,152 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	FreeScope    SymbolScope = "FREE"
+)
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+	// declared holds the names a let further down this scope binds, see
+	// Declare.
+	declared map[string]bool
+
+	FreeSymbols []Symbol
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store:       make(map[string]Symbol),
+		declared:    make(map[string]bool),
+		FreeSymbols: []Symbol{},
+	}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name in this table. Defining a name twice in the same scope
+// reuses the first binding, the same way the evaluator overwrites it in its
+// environment.
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok && (symbol.Scope == GlobalScope || symbol.Scope == LocalScope) {
+		return symbol
+	}
+
+	symbol := Symbol{
+		Name:  name,
+		Index: s.numDefinitions,
+		Scope: LocalScope,
+	}
+
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions += 1
+
+	return symbol
+}
+
+// DefineGlobal binds name in the outermost table, it is how names used before
+// a let statement no enclosing function declares are resolved: the global is
+// set once that statement runs and reading it before then is a runtime error,
+// as in the evaluator.
+func (s *SymbolTable) DefineGlobal(name string) Symbol {
+	global := s
+	for global.Outer != nil {
+		global = global.Outer
+	}
+
+	return global.Define(name)
+}
+
+// Declare records that a let further down this scope binds name. A function
+// nested in the scope that uses name before then captures this binding, like
+// a closure in the evaluator finds it in its environment once it is set,
+// where it would otherwise resolve further out.
+func (s *SymbolTable) Declare(name string) {
+	s.declared[name] = true
+}
+
+// Names returns the names of the symbols of scope defined in this table,
+// indexed the way the vm indexes them.
+func (s *SymbolTable) Names(scope SymbolScope) []string {
+	names := make([]string, s.numDefinitions)
+	for name, symbol := range s.store {
+		if symbol.Scope == scope {
+			names[symbol.Index] = name
+		}
+	}
+
+	return names
+}
+
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{
+		Name:  name,
+		Index: index,
+		Scope: BuiltinScope,
+	}
+	s.store[name] = symbol
+
+	return symbol
+}
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.resolve(name, false)
+}
+
+// resolve looks name up, nested is whether it is used by a function nested
+// in this table's scope rather than in the scope itself.
+func (s *SymbolTable) resolve(name string, nested bool) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && nested && s.declared[name] {
+		return s.Define(name), true
+	}
+
+	if !ok && s.Outer != nil {
+		symbol, ok = s.Outer.resolve(name, true)
+		if !ok {
+			return symbol, ok
+		}
+
+		if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+			return symbol, ok
+		}
+
+		return s.defineFree(symbol), true
+	}
+
+	return symbol, ok
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{
+		Name:  original.Name,
+		Index: len(s.FreeSymbols) - 1,
+		Scope: FreeScope,
+	}
+	s.store[original.Name] = symbol
+
+	return symbol
+}
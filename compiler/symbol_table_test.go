@@ -0,0 +1,111 @@
+package compiler
+
+import "testing"
+
+func TestDefineAndResolve(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	b := local.Define("b")
+
+	nested := NewEnclosedSymbolTable(local)
+	c := nested.Define("c")
+
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: LocalScope, Index: 0},
+		"c": {Name: "c", Scope: LocalScope, Index: 0},
+	}
+
+	for _, symbol := range []Symbol{a, b, c} {
+		if symbol != expected[symbol.Name] {
+			t.Errorf("wrong symbol for %s. expected=%+v, got=%+v", symbol.Name, expected[symbol.Name], symbol)
+		}
+	}
+
+	resolved, ok := nested.Resolve("a")
+	if !ok || resolved != expected["a"] {
+		t.Errorf("a resolved wrongly. expected=%+v, got=%+v", expected["a"], resolved)
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	first := NewEnclosedSymbolTable(global)
+	first.Define("b")
+
+	second := NewEnclosedSymbolTable(first)
+	second.Define("c")
+
+	testCases := []struct {
+		name     string
+		expected Symbol
+	}{
+		{"a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{"b", Symbol{Name: "b", Scope: FreeScope, Index: 0}},
+		{"c", Symbol{Name: "c", Scope: LocalScope, Index: 0}},
+	}
+
+	for _, tc := range testCases {
+		resolved, ok := second.Resolve(tc.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tc.name)
+			continue
+		}
+
+		if resolved != tc.expected {
+			t.Errorf("%s resolved wrongly. expected=%+v, got=%+v", tc.name, tc.expected, resolved)
+		}
+	}
+
+	if len(second.FreeSymbols) != 1 || second.FreeSymbols[0].Name != "b" {
+		t.Errorf("wrong free symbols. got=%+v", second.FreeSymbols)
+	}
+
+	if _, ok := second.Resolve("d"); ok {
+		t.Errorf("d should not be resolvable")
+	}
+}
+
+func TestDefineResolveBuiltins(t *testing.T) {
+	global := NewSymbolTable()
+	nested := NewEnclosedSymbolTable(NewEnclosedSymbolTable(global))
+
+	expected := Symbol{Name: "len", Scope: BuiltinScope, Index: 3}
+	global.DefineBuiltin(3, "len")
+
+	resolved, ok := nested.Resolve("len")
+	if !ok || resolved != expected {
+		t.Errorf("len resolved wrongly. expected=%+v, got=%+v", expected, resolved)
+	}
+}
+
+func TestResolveDeclared(t *testing.T) {
+	global := NewSymbolTable()
+	local := NewEnclosedSymbolTable(global)
+	local.Define("a")
+	local.Declare("b")
+
+	if _, ok := local.Resolve("b"); ok {
+		t.Errorf("b should not be resolvable in its own scope before it is defined")
+	}
+
+	nested := NewEnclosedSymbolTable(local)
+	resolved, ok := nested.Resolve("b")
+	expected := Symbol{Name: "b", Scope: FreeScope, Index: 0}
+	if !ok || resolved != expected {
+		t.Errorf("b resolved wrongly. expected=%+v, got=%+v", expected, resolved)
+	}
+
+	expected = Symbol{Name: "b", Scope: LocalScope, Index: 1}
+	if len(nested.FreeSymbols) != 1 || nested.FreeSymbols[0] != expected {
+		t.Errorf("wrong free symbols. got=%+v", nested.FreeSymbols)
+	}
+
+	if defined := local.Define("b"); defined != expected {
+		t.Errorf("b defined wrongly. expected=%+v, got=%+v", expected, defined)
+	}
+}
@@ -0,0 +1,128 @@
+package diagnostic
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aryuuu/gonkey-lang/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a problem found in the source, located by Span.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Span     token.Span
+	Hints    []string
+	Notes    []string
+}
+
+// Error formats the diagnostic on a single line, e.g.
+// `main.gk:1:9: error[P001]: expected next token to be ), got ; instead`.
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s: %s", d.Span.Start, d.header(), d.Message)
+}
+
+func (d Diagnostic) header() string {
+	if d.Code == "" {
+		return d.Severity.String()
+	}
+
+	return fmt.Sprintf("%s[%s]", d.Severity, d.Code)
+}
+
+// Render writes the diagnostic along with the offending line of source
+// and a caret underline below the reported span.
+func Render(out io.Writer, source string, d Diagnostic) {
+	var buf bytes.Buffer
+
+	start := d.Span.Start
+	buf.WriteString(fmt.Sprintf("%s: %s\n", d.header(), d.Message))
+
+	lines := strings.Split(source, "\n")
+	if !start.IsValid() || start.Line > len(lines) {
+		writeTrailers(&buf, "", d)
+		out.Write(buf.Bytes())
+		return
+	}
+
+	line := strings.TrimRight(lines[start.Line-1], "\r")
+	lineNo := fmt.Sprintf("%d", start.Line)
+	gutter := strings.Repeat(" ", len(lineNo))
+
+	buf.WriteString(fmt.Sprintf("%s--> %s\n", gutter, start))
+	buf.WriteString(fmt.Sprintf("%s |\n", gutter))
+	buf.WriteString(fmt.Sprintf("%s | %s\n", lineNo, line))
+	buf.WriteString(fmt.Sprintf("%s | %s\n", gutter, underline(line, d.Span)))
+	writeTrailers(&buf, gutter, d)
+
+	out.Write(buf.Bytes())
+}
+
+// RenderAll renders every diagnostic in order.
+func RenderAll(out io.Writer, source string, diagnostics []Diagnostic) {
+	for _, d := range diagnostics {
+		Render(out, source, d)
+	}
+}
+
+func writeTrailers(buf *bytes.Buffer, gutter string, d Diagnostic) {
+	for _, hint := range d.Hints {
+		buf.WriteString(fmt.Sprintf("%s = hint: %s\n", gutter, hint))
+	}
+
+	for _, note := range d.Notes {
+		buf.WriteString(fmt.Sprintf("%s = note: %s\n", gutter, note))
+	}
+}
+
+// underline builds the caret line for span within line, keeping tabs from
+// the source so the carets stay aligned with what was printed above. Columns
+// count runes, not bytes.
+func underline(line string, span token.Span) string {
+	chars := []rune(line)
+
+	startCol := span.Start.Column - 1
+	if startCol > len(chars) {
+		startCol = len(chars)
+	}
+
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column-span.Start.Column > 1 {
+		width = span.End.Column - span.Start.Column
+	} else if span.End.Line > span.Start.Line && len(chars)-startCol > 1 {
+		width = len(chars) - startCol
+	}
+
+	var out strings.Builder
+	for _, ch := range chars[:startCol] {
+		if ch == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+	out.WriteString(strings.Repeat("^", width))
+
+	return out.String()
+}
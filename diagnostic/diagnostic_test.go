@@ -0,0 +1,95 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aryuuu/gonkey-lang/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 1;\nlet y = (x + 2;\n"
+	d := Diagnostic{
+		Severity: Error,
+		Code:     "P001",
+		Message:  "expected next token to be ), got ; instead",
+		Span: token.Span{
+			Start: token.Position{File: "main.gk", Line: 2, Column: 15, Offset: 25},
+			End:   token.Position{File: "main.gk", Line: 2, Column: 16, Offset: 26},
+		},
+		Hints: []string{"add a closing )"},
+	}
+
+	expected := `error[P001]: expected next token to be ), got ; instead
+ --> main.gk:2:15
+  |
+2 | let y = (x + 2;
+  |               ^
+  = hint: add a closing )
+`
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering. expected=\n%s\ngot=\n%s", expected, out.String())
+	}
+}
+
+func TestRenderUnderlinesWholeSpan(t *testing.T) {
+	source := "\tfoo + barbaz"
+	d := Diagnostic{
+		Severity: Warning,
+		Message:  "unused",
+		Span: token.Span{
+			Start: token.Position{Line: 1, Column: 8, Offset: 7},
+			End:   token.Position{Line: 1, Column: 14, Offset: 13},
+		},
+	}
+
+	expected := "warning: unused\n --> 1:8\n  |\n1 | \tfoo + barbaz\n  | \t      ^^^^^^\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestRenderCountsRunes(t *testing.T) {
+	source := `let s = "日本" + café;`
+	d := Diagnostic{
+		Severity: Error,
+		Message:  "identifier not found: café",
+		Span: token.Span{
+			Start: token.Position{Line: 1, Column: 16, Offset: 20},
+			End:   token.Position{Line: 1, Column: 20, Offset: 25},
+		},
+	}
+
+	expected := "error: identifier not found: café\n --> 1:16\n  |\n1 | let s = \"日本\" + café;\n  |                ^^^^\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	if out.String() != expected {
+		t.Errorf("wrong rendering. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestError(t *testing.T) {
+	d := Diagnostic{
+		Severity: Error,
+		Code:     "P002",
+		Message:  "no prefix parse function for ) found",
+		Span: token.Span{
+			Start: token.Position{File: "main.gk", Line: 3, Column: 7},
+		},
+	}
+
+	expected := "main.gk:3:7: error[P002]: no prefix parse function for ) found"
+	if d.Error() != expected {
+		t.Errorf("wrong error string. expected=%q, got=%q", expected, d.Error())
+	}
+}
@@ -1,10 +1,25 @@
 package evaluator
 
-import "github.com/aryuuu/gonkey-lang/object"
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// Output is where puts writes, callers embedding the interpreter may replace
+// it before running a program.
+var Output io.Writer = os.Stdout
 
 var builtins = map[string]*object.Builtin{
 	"len": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
@@ -12,19 +27,31 @@ var builtins = map[string]*object.Builtin{
 			switch arg := args[0].(type) {
 			case *object.String:
 				return &object.Integer{
-					Value: int64(len(arg.Value)),
+					Value: int64(utf8.RuneCountInString(arg.Value)),
 				}
 			case *object.Array:
 				return &object.Integer{
 					Value: int64(len(arg.Elements)),
 				}
+			case *object.Map:
+				return &object.Integer{
+					Value: int64(len(arg.Pairs)),
+				}
+			case *object.Tuple:
+				return &object.Integer{
+					Value: int64(len(arg.Elements)),
+				}
+			case *object.Set:
+				return &object.Integer{
+					Value: int64(len(arg.Keys)),
+				}
 			default:
 				return newError("argument to `len` not supported. got %s", args[0].Type())
 			}
 		},
 	},
 	"first": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
@@ -42,7 +69,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
@@ -60,7 +87,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
@@ -83,7 +110,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
@@ -104,4 +131,171 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
+	"int": {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Float:
+				// conversion truncates toward zero
+				integer, ok := floatToInteger(arg.Value)
+				if !ok {
+					return newError("cannot convert %s to INTEGER", arg.Inspect())
+				}
+
+				return integer
+			case *object.String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 10)
+				if !ok {
+					return newError("could not parse %q as integer", arg.Value)
+				}
+
+				return normalizeInteger(value)
+			default:
+				return newError("argument to `int` not supported. got %s", args[0].Type())
+			}
+		},
+	},
+	"float": {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return &object.Float{Value: toFloat(arg)}
+			case *object.Float:
+				return arg
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("could not parse %q as float", arg.Value)
+				}
+
+				return &object.Float{Value: value}
+			default:
+				return newError("argument to `float` not supported. got %s", args[0].Type())
+			}
+		},
+	},
+	// strings are sequences of runes everywhere else, these two work on
+	// their UTF-8 encoding
+	"bytes": {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `bytes` must be STRING, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, len(str.Value))
+			for i := 0; i < len(str.Value); i++ {
+				elements[i] = &object.Integer{Value: int64(str.Value[i])}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"byte_len": {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `byte_len` must be STRING, got %s", args[0].Type())
+			}
+
+			return &object.Integer{Value: int64(len(str.Value))}
+		},
+	},
+	"split":       {Fn: builtinSplit},
+	"join":        {Fn: builtinJoin},
+	"trim":        {Fn: builtinTrim},
+	"upper":       {Fn: builtinUpper},
+	"lower":       {Fn: builtinLower},
+	"contains":    {Fn: builtinContains},
+	"starts_with": {Fn: builtinStartsWith},
+	"ends_with":   {Fn: builtinEndsWith},
+	"replace":     {Fn: builtinReplace},
+	"index_of":    {Fn: builtinIndexOf},
+	"substr":      {Fn: builtinSubstr},
+	"repeat":      {Fn: builtinRepeat},
+	"format":      {Fn: builtinFormat},
+	"map":         {Fn: builtinMap},
+	"filter":      {Fn: builtinFilter},
+	"reduce":      {Fn: builtinReduce},
+	"sort":        {Fn: builtinSort},
+	"sort_by":     {Fn: builtinSortBy},
+	"any":         {Fn: builtinAny},
+	"all":         {Fn: builtinAll},
+	"zip":         {Fn: builtinZip},
+	"range":       {Fn: builtinRange},
+	"reverse":     {Fn: builtinReverse},
+	"flatten":     {Fn: builtinFlatten},
+	"unique":      {Fn: builtinUnique},
+	"keys":        {Fn: builtinKeys},
+	"values":      {Fn: builtinValues},
+	"entries":     {Fn: builtinEntries},
+	"has":         {Fn: builtinHas},
+	"delete":      {Fn: builtinDelete},
+	"merge":       {Fn: builtinMerge},
+	"set":         {Fn: builtinSet},
+	"tuple":       {Fn: builtinTuple},
+	"puts": {
+		Fn: func(_ object.Caller, args ...object.Object) object.Object {
+			for _, arg := range args {
+				fmt.Fprintln(Output, arg.Inspect())
+			}
+
+			return NULL
+		},
+	},
+}
+
+// checkArgs validates the number and types of the arguments to the builtin
+// name.
+func checkArgs(name string, args []object.Object, types ...object.ObjectType) *object.Error {
+	if len(args) != len(types) {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), len(types))
+	}
+
+	for i, t := range types {
+		// builtins are as good as functions wherever one is expected
+		if args[i].Type() == t || t == object.FUNCTION_OBJ && args[i].Type() == object.BUILTIN_OBJ {
+			continue
+		}
+
+		if len(types) == 1 {
+			return newError("argument to `%s` must be %s, got %s", name, t, args[i].Type())
+		}
+		return newError("argument %d to `%s` must be %s, got %s", i+1, name, t, args[i].Type())
+	}
+
+	return nil
+}
+
+// saturatedInt64 returns the value of an integer, a BigInt is clamped to the
+// int64 range which is out of range for any string or array anyway.
+func saturatedInt64(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInt:
+		if obj.Value.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	default:
+		return 0
+	}
 }
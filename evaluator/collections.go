@@ -0,0 +1,339 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// The collection builtins, they never modify the arrays passed to them.
+// Those taking a function call it through the Caller of the engine running
+// them and stop at the first error it returns.
+
+// maxRangeLength caps the number of elements range builds.
+const maxRangeLength = 1 << 26
+
+func builtinMap(call object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("map", args, object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*object.Array).Elements
+	result := make([]object.Object, len(elements))
+	for i, element := range elements {
+		value := call(args[1], []object.Object{element})
+		if isError(value) {
+			return value
+		}
+		result[i] = value
+	}
+
+	return &object.Array{Elements: result}
+}
+
+func builtinFilter(call object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("filter", args, object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	result := []object.Object{}
+	for _, element := range args[0].(*object.Array).Elements {
+		keep := call(args[1], []object.Object{element})
+		if isError(keep) {
+			return keep
+		}
+
+		if isTruthy(keep) {
+			result = append(result, element)
+		}
+	}
+
+	return &object.Array{Elements: result}
+}
+
+// builtinReduce folds the array from the left, starting from the initial
+// value when one is given and from the first element otherwise.
+func builtinReduce(call object.Caller, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	if err := checkArgs("reduce", args[:2], object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*object.Array).Elements
+
+	var acc object.Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else if len(elements) > 0 {
+		acc, elements = elements[0], elements[1:]
+	} else {
+		return newError("`reduce` of an empty array needs an initial value")
+	}
+
+	for _, element := range elements {
+		acc = call(args[1], []object.Object{acc, element})
+		if isError(acc) {
+			return acc
+		}
+	}
+
+	return acc
+}
+
+// builtinSort sorts numbers and strings in ascending order, the sort is
+// stable.
+func builtinSort(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("sort", args, object.ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*object.Array).Elements
+	return sortedBy(elements, elements)
+}
+
+// builtinSortBy sorts the elements by the key the function returns for
+// each of them, the function is called once per element.
+func builtinSortBy(call object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("sort_by", args, object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*object.Array).Elements
+	keys := make([]object.Object, len(elements))
+	for i, element := range elements {
+		key := call(args[1], []object.Object{element})
+		if isError(key) {
+			return key
+		}
+		keys[i] = key
+	}
+
+	return sortedBy(elements, keys)
+}
+
+// sortedBy returns a copy of elements in the order of their keys, keys are
+// compared with < so the sort fails where that would. Neighbouring keys are
+// checked first, so a mismatch is reported in the order of the elements.
+func sortedBy(elements, keys []object.Object) object.Object {
+	for i := 1; i < len(keys); i++ {
+		if less := evalInfixExpression("<", keys[i-1], keys[i]); isError(less) {
+			return less
+		}
+	}
+
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return evalInfixExpression("<", keys[order[i]], keys[order[j]]) == TRUE
+	})
+
+	result := make([]object.Object, len(elements))
+	for i, idx := range order {
+		result[i] = elements[idx]
+	}
+
+	return &object.Array{Elements: result}
+}
+
+func builtinAny(call object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("any", args, object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	for _, element := range args[0].(*object.Array).Elements {
+		result := call(args[1], []object.Object{element})
+		if isError(result) {
+			return result
+		}
+
+		if isTruthy(result) {
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+func builtinAll(call object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("all", args, object.ARRAY_OBJ, object.FUNCTION_OBJ); err != nil {
+		return err
+	}
+
+	for _, element := range args[0].(*object.Array).Elements {
+		result := call(args[1], []object.Object{element})
+		if isError(result) {
+			return result
+		}
+
+		if !isTruthy(result) {
+			return FALSE
+		}
+	}
+
+	return TRUE
+}
+
+// builtinZip pairs up the elements of two arrays, the result is as long as
+// the shorter one.
+func builtinZip(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("zip", args, object.ARRAY_OBJ, object.ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	left := args[0].(*object.Array).Elements
+	right := args[1].(*object.Array).Elements
+
+	length := len(left)
+	if len(right) < length {
+		length = len(right)
+	}
+
+	result := make([]object.Object, length)
+	for i := range result {
+		result[i] = &object.Array{Elements: []object.Object{left[i], right[i]}}
+	}
+
+	return &object.Array{Elements: result}
+}
+
+// builtinRange returns the integers from start up to but not including end,
+// range(end) starts at 0 and the step defaults to 1.
+func builtinRange(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+	}
+
+	for i, arg := range args {
+		if arg.Type() != object.INTEGER_OBJ {
+			return newError("argument %d to `range` must be INTEGER, got %s", i+1, arg.Type())
+		}
+	}
+
+	start, end, step := int64(0), saturatedInt64(args[0]), int64(1)
+	if len(args) > 1 {
+		start, end = saturatedInt64(args[0]), saturatedInt64(args[1])
+	}
+	if len(args) > 2 {
+		step = saturatedInt64(args[2])
+	}
+
+	if step == 0 {
+		return newError("step of `range` must not be zero")
+	}
+
+	// count in uint64 so ranges spanning most of the int64 range do not
+	// overflow
+	var distance, stride uint64
+	switch {
+	case step > 0 && start < end:
+		distance, stride = uint64(end)-uint64(start), uint64(step)
+	case step < 0 && start > end:
+		distance, stride = uint64(start)-uint64(end), uint64(-step)
+	default:
+		return &object.Array{Elements: []object.Object{}}
+	}
+
+	count := distance / stride
+	if distance%stride != 0 {
+		count += 1
+	}
+	if count > maxRangeLength {
+		return newError("result of `range` is too long")
+	}
+
+	result := make([]object.Object, count)
+	for i := range result {
+		result[i] = &object.Integer{Value: start + int64(i)*step}
+	}
+
+	return &object.Array{Elements: result}
+}
+
+// builtinReverse reverses an array, or the characters of a string.
+func builtinReverse(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		result := make([]object.Object, len(arg.Elements))
+		for i, element := range arg.Elements {
+			result[len(result)-1-i] = element
+		}
+
+		return &object.Array{Elements: result}
+	case *object.String:
+		chars := []rune(arg.Value)
+		for i, j := 0, len(chars)-1; i < j; i, j = i+1, j-1 {
+			chars[i], chars[j] = chars[j], chars[i]
+		}
+
+		return &object.String{Value: string(chars)}
+	default:
+		return newError("argument to `reverse` not supported. got %s", args[0].Type())
+	}
+}
+
+// builtinFlatten removes one level of nesting, elements that are not arrays
+// are kept as they are.
+func builtinFlatten(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("flatten", args, object.ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	result := []object.Object{}
+	for _, element := range args[0].(*object.Array).Elements {
+		if inner, ok := element.(*object.Array); ok {
+			result = append(result, inner.Elements...)
+		} else {
+			result = append(result, element)
+		}
+	}
+
+	return &object.Array{Elements: result}
+}
+
+// builtinUnique drops the elements equal to an earlier one, as compared by
+// ==, and keeps the order of the rest.
+func builtinUnique(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("unique", args, object.ARRAY_OBJ); err != nil {
+		return err
+	}
+
+	result := []object.Object{}
+	seen := map[object.HashKey]bool{}
+	// values that cannot be hashed are compared against each other instead
+	unhashable := []object.Object{}
+
+	for _, element := range args[0].(*object.Array).Elements {
+		if key, ok := object.HashKeyOf(element); ok {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		} else {
+			duplicate := false
+			for _, other := range unhashable {
+				if objectsEqual(element, other) {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				continue
+			}
+			unhashable = append(unhashable, element)
+		}
+
+		result = append(result, element)
+	}
+
+	return &object.Array{Elements: result}
+}
@@ -2,6 +2,10 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/aryuuu/gonkey-lang/ast"
 	"github.com/aryuuu/gonkey-lang/object"
@@ -17,9 +21,24 @@ var (
 	}
 
 	NULL = &object.Null{}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+
+	// errors bubble up from the innermost node, so the first node to see an
+	// error without a position is the one that raised it
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// statements
@@ -35,8 +54,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
+		nameFunction(val, node.Name.Value)
 		env.Set(node.Name.Value, val)
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -52,14 +80,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// expressions
 	case *ast.Boolean:
 		return nativeBooleanToObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{
 			Value: node.Value,
 		}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{
 			Value: node.Value,
 		}
+	case *ast.InterpolatedString:
+		parts := evalExpressions(node.Parts, env)
+		if len(parts) == 1 && isError(parts[0]) {
+			return parts[0]
+		}
+
+		return interpolate(parts)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -69,6 +111,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Array{
 			Elements: elements,
 		}
+	case *ast.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+
+		return &object.Tuple{
+			Elements: elements,
+		}
+	case *ast.SetLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+
+		return newSet(elements)
 	case *ast.MapLiteral:
 		return evalMapLiteral(node, env)
 	case *ast.FunctionLiteral:
@@ -78,16 +136,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Env:        env,
 		}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
-
-		return applyFunction(function, args)
+		result, _ := evalCallNode(node, env)
+		return result
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -95,6 +145,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -109,17 +163,15 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-
-		return evalIndexExpression(left, index)
+		result, _ := evalIndexNode(node, env)
+		return result
+	case *ast.SliceExpression:
+		result, _ := evalSliceNode(node, env)
+		return result
+	case *ast.GroupedExpression:
+		return Eval(node.Expression, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	}
 
 	return nil
@@ -164,12 +216,19 @@ func evalBlockStatement(blockStatement *ast.BlockStatement, env *object.Environm
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ {
+			if rt == object.ERROR_OBJ || rt == object.RETURN_VALUE_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
 	}
 
+	// a block that is empty or ends with a let statement has no value of its
+	// own, it is null like in the vm
+	if result == nil {
+		return NULL
+	}
+
 	return result
 }
 
@@ -202,12 +261,16 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left, right)
 	case operator == "==":
-		return nativeBooleanToObject(left == right)
+		return nativeBooleanToObject(objectsEqual(left, right))
 	case operator == "!=":
-		return nativeBooleanToObject(left != right)
+		return nativeBooleanToObject(!objectsEqual(left, right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -215,30 +278,56 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	}
 }
 
+// evalIntegerInfixExpression works on int64 values as long as the result
+// fits, promoting the operation to arbitrary precision once it overflows.
+//
+// Division floors, rounding toward negative infinity rather than toward zero,
+// and the remainder takes the sign of the divisor, so that a == (a / b) * b +
+// a % b always holds: -7 / 2 is -4 and -7 % 2 is 1.
 func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	leftInt, leftOk := left.(*object.Integer)
+	rightInt, rightOk := right.(*object.Integer)
+	if !leftOk || !rightOk {
+		return evalBigIntegerInfixExpression(operator, toBigInt(left), toBigInt(right))
+	}
+
+	leftVal := leftInt.Value
+	rightVal := rightInt.Value
 	switch operator {
 	case "+":
-		return &object.Integer{
-			Value: leftVal + rightVal,
+		if result, ok := addInt64(leftVal, rightVal); ok {
+			return &object.Integer{Value: result}
 		}
 	case "-":
-		return &object.Integer{
-			Value: leftVal - rightVal,
+		if result, ok := subInt64(leftVal, rightVal); ok {
+			return &object.Integer{Value: result}
 		}
 	case "*":
-		return &object.Integer{
-			Value: leftVal * rightVal,
+		if result, ok := mulInt64(leftVal, rightVal); ok {
+			return &object.Integer{Value: result}
 		}
 	case "/":
-		return &object.Integer{
-			Value: leftVal / rightVal,
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+
+		if leftVal != math.MinInt64 || rightVal != -1 {
+			return &object.Integer{Value: floorDivInt64(leftVal, rightVal)}
 		}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+
+		return &object.Integer{Value: floorModInt64(leftVal, rightVal)}
 	case ">":
 		return nativeBooleanToObject(leftVal > rightVal)
 	case "<":
 		return nativeBooleanToObject(leftVal < rightVal)
+	case ">=":
+		return nativeBooleanToObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBooleanToObject(leftVal <= rightVal)
 	case "==":
 		return nativeBooleanToObject(leftVal == rightVal)
 	case "!=":
@@ -246,6 +335,72 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+
+	return evalBigIntegerInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+}
+
+// evalFloatInfixExpression handles two floats, or a float and an integer in
+// which case the integer is converted to a float first.
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+
+		mod := math.Mod(leftVal, rightVal)
+		if mod != 0 && (mod < 0) != (rightVal < 0) {
+			mod += rightVal
+		}
+
+		return &object.Float{Value: mod}
+	case ">":
+		return nativeBooleanToObject(leftVal > rightVal)
+	case "<":
+		return nativeBooleanToObject(leftVal < rightVal)
+	case ">=":
+		return nativeBooleanToObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBooleanToObject(leftVal <= rightVal)
+	case "==":
+		return nativeBooleanToObject(leftVal == rightVal)
+	case "!=":
+		return nativeBooleanToObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
 }
 
 func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
@@ -256,11 +411,127 @@ func evalStringInfixExpression(operator string, left object.Object, right object
 		return &object.String{
 			Value: leftVal + rightVal,
 		}
+	case "==":
+		return nativeBooleanToObject(leftVal == rightVal)
+	case "!=":
+		return nativeBooleanToObject(leftVal != rightVal)
+	case ">":
+		return nativeBooleanToObject(leftVal > rightVal)
+	case "<":
+		return nativeBooleanToObject(leftVal < rightVal)
+	case ">=":
+		return nativeBooleanToObject(leftVal >= rightVal)
+	case "<=":
+		return nativeBooleanToObject(leftVal <= rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// interpolate joins the parts of an interpolated string, they are formatted
+// the way the REPL prints them.
+func interpolate(parts []object.Object) *object.String {
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(part.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// objectsEqual compares by value, arrays and maps are equal when their
+// elements are, anything else without a value of its own like a function is
+// only equal to itself.
+func objectsEqual(left object.Object, right object.Object) bool {
+	return valuesEqual(left, right, map[comparedPair]bool{})
+}
+
+// comparedPair is a pair of collections met by valuesEqual.
+type comparedPair struct {
+	left, right object.Object
+}
+
+// valuesEqual is objectsEqual for values nested in the collections being
+// compared. Arrays and maps can contain themselves, so a pair of collections
+// met a second time is taken as equal and the comparison ends, a difference
+// between them shows up where they were first met.
+func valuesEqual(left object.Object, right object.Object, comparing map[comparedPair]bool) bool {
+	if left == right {
+		return true
+	}
+
+	switch left.(type) {
+	case *object.Array, *object.Tuple, *object.Map:
+		pair := comparedPair{left, right}
+		if comparing[pair] {
+			return true
+		}
+		comparing[pair] = true
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		right, ok := right.(*object.Array)
+		if !ok || len(left.Elements) != len(right.Elements) {
+			return false
+		}
+
+		for i, element := range left.Elements {
+			if !valuesEqual(element, right.Elements[i], comparing) {
+				return false
+			}
+		}
+
+		return true
+	case *object.Tuple:
+		right, ok := right.(*object.Tuple)
+		if !ok || len(left.Elements) != len(right.Elements) {
+			return false
+		}
+
+		for i, element := range left.Elements {
+			if !valuesEqual(element, right.Elements[i], comparing) {
+				return false
+			}
+		}
+
+		return true
+	case *object.Set:
+		right, ok := right.(*object.Set)
+		if !ok || len(left.Keys) != len(right.Keys) {
+			return false
+		}
+
+		for _, key := range left.Keys {
+			if !right.Has(key) {
+				return false
+			}
+		}
+
+		return true
+	case *object.Map:
+		right, ok := right.(*object.Map)
+		if !ok || len(left.Pairs) != len(right.Pairs) {
+			return false
+		}
+
+		for key, pair := range left.Pairs {
+			other, ok := right.Pairs[key]
+			if !ok || !valuesEqual(pair.Value, other.Value, comparing) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if isNumber(left) && isNumber(right) || left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
+		return evalInfixExpression("==", left, right) == TRUE
+	}
+
+	return false
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -275,14 +546,41 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return normalizeInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return normalizeInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{
-		Value: -value,
+// evalLogicalExpression short-circuits &&, || and ??, the right operand is only
+// evaluated when the left one does not decide the result. The result is the
+// operand that decided it rather than a boolean, so `name || "anonymous"`
+// picks the first truthy value. `??` works like `||` but only skips null, so
+// `count ?? 10` keeps a count of 0.
+func evalLogicalExpression(ie *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(ie.Left, env)
+	if isError(left) {
+		return left
 	}
+
+	if ie.Operator == "??" {
+		if left != NULL {
+			return left
+		}
+	} else if isTruthy(left) == (ie.Operator == "||") {
+		return left
+	}
+
+	return Eval(ie.Right, env)
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -300,12 +598,104 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(ws.Body, env)
+		if stop, ok := loopResult(result); ok {
+			return stop
+		}
+	}
+}
+
+// evalForStatement binds each element to the loop variable in the current
+// scope, the variable is still visible once the loop is done.
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	elements, err := iterableElements(iterable)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range elements {
+		nameFunction(el, fs.Variable.Value)
+		env.Set(fs.Variable.Value, el)
+
+		result := Eval(fs.Body, env)
+		if stop, ok := loopResult(result); ok {
+			return stop
+		}
+	}
+
+	return NULL
+}
+
+// loopResult inspects the result of a loop body, reporting whether the loop
+// has to stop and with which value.
+func loopResult(result object.Object) (object.Object, bool) {
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return NULL, true
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// iterableElements returns the values a for loop visits: the elements of an
+// array, tuple or set, the characters of a string or the keys of a map.
+func iterableElements(obj object.Object) ([]object.Object, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return obj.Elements, nil
+	case *object.Tuple:
+		return obj.Elements, nil
+	case *object.Set:
+		return obj.OrderedElements(), nil
+	case *object.String:
+		elements := make([]object.Object, 0, len(obj.Value))
+		for _, ch := range obj.Value {
+			elements = append(elements, &object.String{Value: string(ch)})
+		}
+		return elements, nil
+	case *object.Map:
+		elements := make([]object.Object, 0, len(obj.Keys))
+		for _, pair := range obj.OrderedPairs() {
+			elements = append(elements, pair.Key)
+		}
+		return elements, nil
+	default:
+		return nil, newError("not iterable: %s", obj.Type())
+	}
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalTupleIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.MAP_OBJ:
-	return evalMapIndexExpression(left, index)
+		return evalMapIndexExpression(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -313,56 +703,344 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
 
-	if idx < 0 || idx > max {
+	idx, ok := elementIndex(index, int64(len(arrayObject.Elements)))
+	if !ok {
 		return NULL
 	}
 
 	return arrayObject.Elements[idx]
 }
 
+func evalTupleIndexExpression(tuple, index object.Object) object.Object {
+	tupleObject := tuple.(*object.Tuple)
+
+	idx, ok := elementIndex(index, int64(len(tupleObject.Elements)))
+	if !ok {
+		return NULL
+	}
+
+	return tupleObject.Elements[idx]
+}
+
+// evalStringIndexExpression indexes a string by runes rather than bytes,
+// the result is the character at that position as a string.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	value := str.(*object.String).Value
+
+	idx, ok := elementIndex(index, int64(utf8.RuneCountInString(value)))
+	if !ok {
+		return NULL
+	}
+
+	i := int64(0)
+	for _, ch := range value {
+		if i == idx {
+			return &object.String{Value: string(ch)}
+		}
+		i += 1
+	}
+
+	return NULL
+}
+
+// elementIndex resolves an index into a sequence of the given length, a
+// negative index counts from the end. It reports false when the index is out
+// of range, which a BigInt always is.
+func elementIndex(index object.Object, length int64) (int64, bool) {
+	integer, ok := index.(*object.Integer)
+	if !ok {
+		return 0, false
+	}
+
+	idx := integer.Value
+	if idx < 0 {
+		idx += length
+	}
+
+	return idx, idx >= 0 && idx < length
+}
+
+// evalChain evaluates an expression that may be a link in a chain of calls,
+// indexes and slices. It reports whether an optional link of the chain met
+// null, the links after it are skipped and the whole chain is null.
+func evalChain(node ast.Expression, env *object.Environment) (object.Object, bool) {
+	var result object.Object
+	var skipped bool
+
+	switch node := node.(type) {
+	case *ast.CallExpression:
+		result, skipped = evalCallNode(node, env)
+	case *ast.IndexExpression:
+		result, skipped = evalIndexNode(node, env)
+	case *ast.SliceExpression:
+		result, skipped = evalSliceNode(node, env)
+	default:
+		return Eval(node, env), false
+	}
+
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result, skipped
+}
+
+func evalCallNode(node *ast.CallExpression, env *object.Environment) (object.Object, bool) {
+	function, skipped := evalChain(node.Function, env)
+	if skipped || isError(function) {
+		return function, skipped
+	}
+
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0], false
+	}
+
+	result := applyFunction(function, args)
+	if err, ok := result.(*object.Error); ok {
+		if fn, ok := function.(*object.Function); ok {
+			err.Stack = append(err.Stack, object.Frame{
+				Function: fn.Name,
+				Pos:      node.Pos(),
+			})
+		}
+	}
+
+	return result, false
+}
+
+func evalIndexNode(node *ast.IndexExpression, env *object.Environment) (object.Object, bool) {
+	left, skipped := evalChain(node.Left, env)
+	if skipped || isError(left) {
+		return left, skipped
+	}
+
+	// a?[i] skips the index, and the rest of the chain, when a is null
+	if node.Optional && left == NULL {
+		return NULL, true
+	}
+
+	index := Eval(node.Index, env)
+	if isError(index) {
+		return index, false
+	}
+
+	return evalIndexExpression(left, index), false
+}
+
+func evalSliceNode(node *ast.SliceExpression, env *object.Environment) (object.Object, bool) {
+	left, skipped := evalChain(node.Left, env)
+	if skipped || isError(left) {
+		return left, skipped
+	}
+
+	if node.Optional && left == NULL {
+		return NULL, true
+	}
+
+	// a bound that is left out is passed on as NULL
+	bounds := []object.Object{NULL, NULL}
+	for i, bound := range []ast.Expression{node.Low, node.High} {
+		if bound == nil {
+			continue
+		}
+
+		bounds[i] = Eval(bound, env)
+		if isError(bounds[i]) {
+			return bounds[i], false
+		}
+	}
+
+	return evalSliceExpression(left, bounds[0], bounds[1]), false
+}
+
+// evalSliceExpression returns the elements or characters from low up to but
+// not including high. NULL stands for the start or the end, negative bounds
+// count from the end and bounds past either end are clamped to it.
+func evalSliceExpression(left, low, high object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		from, to, err := sliceBounds(low, high, int64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+
+		elements := make([]object.Object, to-from)
+		copy(elements, left.Elements[from:to])
+
+		return &object.Array{Elements: elements}
+	case *object.Tuple:
+		from, to, err := sliceBounds(low, high, int64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+
+		// tuples are immutable, so the slice can share the elements
+		return &object.Tuple{Elements: left.Elements[from:to:to]}
+	case *object.String:
+		chars := []rune(left.Value)
+		from, to, err := sliceBounds(low, high, int64(len(chars)))
+		if err != nil {
+			return err
+		}
+
+		return &object.String{Value: string(chars[from:to])}
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+func sliceBounds(low, high object.Object, length int64) (int64, int64, *object.Error) {
+	from, err := sliceBound(low, 0, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	to, err := sliceBound(high, length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if to < from {
+		to = from
+	}
+
+	return from, to, nil
+}
+
+func sliceBound(bound object.Object, fallback, length int64) (int64, *object.Error) {
+	if bound == NULL {
+		return fallback, nil
+	}
+
+	if bound.Type() != object.INTEGER_OBJ {
+		return 0, newError("slice index must be INTEGER, got %s", bound.Type())
+	}
+
+	idx := saturatedInt64(bound)
+	if idx < 0 {
+		idx += length
+	}
+
+	if idx < 0 {
+		return 0, nil
+	}
+	if idx > length {
+		return length, nil
+	}
+
+	return idx, nil
+}
+
 func evalMapLiteral(node *ast.MapLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	m := object.NewMap()
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.HashKeyOf(key)
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{
+		m.Set(hashKey, object.HashPair{
 			Key:   key,
 			Value: value,
+		})
+	}
+
+	return m
+}
+
+// nameFunction names a function after the variable it is bound to, unless
+// it already has a name. Stack traces show the name.
+func nameFunction(obj object.Object, name string) {
+	if fn, ok := obj.(*object.Function); ok && fn.Name == "" {
+		fn.Name = name
+	}
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		if !env.Assign(target.Value, value) {
+			return newError("assignment to undeclared identifier: %s", target.Value)
+		}
+		nameFunction(value, target.Value)
+
+		return value
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
 		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		return evalIndexAssignment(left, index, value)
+	default:
+		return newError("cannot assign to %s", node.Target.String())
 	}
+}
+
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		arrayObject := left.(*object.Array)
+
+		idx, ok := elementIndex(index, int64(len(arrayObject.Elements)))
+		if !ok {
+			return newError("index out of range: %s", index.Inspect())
+		}
 
-	return &object.Map{
-		Pairs: pairs,
+		arrayObject.Elements[idx] = value
+		return value
+	case left.Type() == object.MAP_OBJ:
+		mapObject := left.(*object.Map)
+
+		key, ok := object.HashKeyOf(index)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		mapObject.Set(key, object.HashPair{Key: index, Value: value})
+		return value
+	default:
+		return newError("index assignment not supported: %s", left.Type())
 	}
 }
 
 func evalMapIndexExpression(mapObj, index object.Object) object.Object {
 	mapObject := mapObj.(*object.Map)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.HashKeyOf(index)
 	if !ok {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := mapObject.Pairs[key.HashKey()]
+	pair, ok := mapObject.Pairs[key]
 	if !ok {
 		return NULL
 	}
@@ -420,11 +1098,15 @@ func isError(obj object.Object) bool {
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
+
 		extendedEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Fn(applyFunction, args...)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
@@ -440,10 +1122,16 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	return env
 }
 
+// unwrapReturnValue turns the value of a function body into the result of
+// the call, a body that produces no value returns null.
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
 	}
 
+	if obj == nil {
+		return NULL
+	}
+
 	return obj
 }
@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"bytes"
+	"os"
 	"testing"
 
 	"github.com/aryuuu/gonkey-lang/lexer"
@@ -81,6 +83,219 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"-2.5", -2.5},
+		{"1e3", 1000},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"3 * 0.5", 1.5},
+		{"2.5 - 3", -0.5},
+		{"(1.5 + 2) * -2", -7},
+		{"float(3) / 2", 1.5},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testFloatObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestFloatComparisons(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2.5", true},
+		{"1.5 > 2.5", false},
+		{"2 == 2.0", true},
+		{"2.0 != 2", false},
+		{"1 < 1.5", true},
+		{"1.5 > 1", true},
+		{"0.1 + 0.2 == 0.3", false},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testBooleanObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestNumberConversions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"int(3)", int64(3)},
+		{"int(3.9)", int64(3)},
+		{"int(-3.9)", int64(-3)},
+		{`int(" 42 ")`, int64(42)},
+		{"float(2)", 2.0},
+		{"float(2.5)", 2.5},
+		{`float("1e-3")`, 0.001},
+		{`int("4.5")`, `could not parse "4.5" as integer`},
+		{`float("abc")`, `could not parse "abc" as float`},
+		{`int(float("inf"))`, "cannot convert +Inf to INTEGER"},
+		{"int(true)", "argument to `int` not supported. got BOOLEAN"},
+		{"float([])", "argument to `float` not supported. got ARRAY"},
+		{"int(1, 2)", "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not error, got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"99999999999999999999", "99999999999999999999"},
+		{"99999999999999999999 * 10 + 9", "999999999999999999999"},
+		{"99999999999999999999 / 3", "33333333333333333333"},
+		{"-99999999999999999999", "-99999999999999999999"},
+		{`int("123456789012345678901234567890")`, "123456789012345678901234567890"},
+		{"int(1e20)", "100000000000000000000"},
+		{
+			`let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+			fact(30);`,
+			"265252859812191058636308480000000",
+		},
+		{
+			`let p = 1;
+			let i = 0;
+			while (i < 100) { p = p * 2; i = i + 1; }
+			p;`,
+			"1267650600228229401496703205376",
+		},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		integer, ok := evaluated.(*object.BigInt)
+		if !ok {
+			t.Errorf("%q: object is not BigInt, got=%T (%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if integer.Inspect() != tc.expected {
+			t.Errorf("%q: wrong value. expected=%s, got=%s", tc.input, tc.expected, integer.Inspect())
+		}
+	}
+}
+
+func TestDivisionAndModulo(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"7 / 2", int64(3)},
+		{"-7 / 2", int64(-4)},
+		{"7 / -2", int64(-4)},
+		{"-7 / -2", int64(3)},
+		{"-6 / 2", int64(-3)},
+		{"7 % 3", int64(1)},
+		{"-7 % 3", int64(2)},
+		{"7 % -3", int64(-2)},
+		{"-7 % -3", int64(-1)},
+		{"-6 % 3", int64(0)},
+		{"(-9223372036854775807 - 1) % -1", int64(0)},
+		{"2 + 7 % 4 * 3", int64(11)},
+		{"let a = -17; let b = 5; (a / b) * b + a % b", int64(-17)},
+		{"-99999999999999999999 / 7", "-14285714285714285715"},
+		{"-99999999999999999999 % 7", int64(6)},
+		{"99999999999999999999 % -7", int64(-6)},
+		{"99999999999999999999 % 100000000000000000000", "99999999999999999999"},
+		{"7.5 % 2", 1.5},
+		{"-7.5 % 2", 0.5},
+		{"7 % -2.5", -0.5},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			bigInt, ok := evaluated.(*object.BigInt)
+			if !ok {
+				t.Errorf("%q: obj should be of type object.BigInt, got=%T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			if bigInt.Inspect() != expected {
+				t.Errorf("%q: obj value should be %s, got=%s", tc.input, expected, bigInt.Inspect())
+			}
+		}
+	}
+}
+
+func TestBigIntegerDemotion(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"9223372036854775808 - 1", int64(9223372036854775807)},
+		{"99999999999999999999 - 99999999999999999998", int64(1)},
+		{"-9223372036854775808", int64(-9223372036854775807 - 1)},
+		{"99999999999999999999 > 1", true},
+		{"99999999999999999999 < -1", false},
+		{"99999999999999999999 == 99999999999999999999", true},
+		{"99999999999999999999 != 99999999999999999998", true},
+		{"99999999999999999999 > 1.5", true},
+		{"float(99999999999999999999)", 1e20},
+		{"[1, 2][99999999999999999999]", nil},
+		{"{99999999999999999999: 1}[99999999999999999999]", int64(1)},
+		{"{1: 1}[99999999999999999999]", nil},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -166,6 +381,407 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestClosures(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input: `
+			let newAdder = fn(x) { fn(y) { x + y }; };
+			let addTwo = newAdder(2);
+			addTwo(3);`,
+			expected: 5,
+		},
+		{
+			input: `
+			let newAdder = fn(x) { fn(y) { x + y }; };
+			let addOne = newAdder(1);
+			let addTen = newAdder(10);
+			addOne(1) + addTen(1);`,
+			expected: 13,
+		},
+		{
+			input: `
+			let global = 10;
+			let f = fn() { let local = 5; fn() { global + local }; };
+			f()();`,
+			expected: 15,
+		},
+		{
+			input: `
+			let add = fn(a) { fn(b) { fn(c) { a + b + c }; }; };
+			add(1)(2)(3);`,
+			expected: 6,
+		},
+		{
+			input: `
+			let curry = fn(f) { fn(a) { fn(b) { f(a, b) }; }; };
+			let mul = curry(fn(a, b) { a * b });
+			let triple = mul(3);
+			triple(7);`,
+			expected: 21,
+		},
+		{
+			input: `
+			let counter = fn(n) { {"value": n, "next": fn() { counter(n + 1) }} };
+			let c = counter(0)["next"]()["next"]();
+			c["value"];`,
+			expected: 2,
+		},
+		{
+			input: `
+			let c = fn() { 1 };
+			let makeCaller = fn() { fn() { c() }; };
+			let caller = makeCaller();
+			let c = fn() { 2 };
+			caller();`,
+			expected: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestShadowing(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input:    "let x = 1; let f = fn(x) { x }; f(2);",
+			expected: 2,
+		},
+		{
+			input:    "let x = 1; let f = fn(x) { x }; f(2); x;",
+			expected: 1,
+		},
+		{
+			input:    "let x = 1; let f = fn() { let x = 2; x }; f() * 10 + x;",
+			expected: 21,
+		},
+		{
+			input: `
+			let x = 1;
+			let outer = fn() { let x = 2; fn() { x } };
+			outer()();`,
+			expected: 2,
+		},
+		{
+			input: `
+			let x = 1;
+			let f = fn() { x };
+			let g = fn() { let x = 2; f() };
+			g();`,
+			expected: 1,
+		},
+		{
+			input:    "let x = 1; let x = x + 1; x;",
+			expected: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestRecursiveClosures(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input: `
+			let fibonacci = fn(x) {
+				if (x < 2) { return x; }
+				fibonacci(x - 1) + fibonacci(x - 2)
+			};
+			fibonacci(15);`,
+			expected: 610,
+		},
+		{
+			input: `
+			let wrapper = fn() {
+				let countDown = fn(x) {
+					if (x == 0) { return 0; }
+					countDown(x - 1);
+				};
+				countDown(10);
+			};
+			wrapper();`,
+			expected: 0,
+		},
+		{
+			input: `
+			let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+			let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+			if (isEven(10)) { 1 } else { 0 };`,
+			expected: 1,
+		},
+		{
+			input: `
+			let sumTo = fn(limit) {
+				let loop = fn(i, acc) { if (i > limit) { acc } else { loop(i + 1, acc + i) } };
+				loop(1, 0);
+			};
+			sumTo(100);`,
+			expected: 5050,
+		},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let x = 1; x = 2; x;", 2},
+		{"let x = 1; x = x + 1;", 2},
+		{"let x = 1; let y = 2; x = y = 3; x + y;", 6},
+		{"let x = 1; let f = fn() { x = 10; }; f(); x;", 10},
+		{"let x = 1; let f = fn() { let x = 5; x = 10; }; f(); x;", 1},
+		{"let f = fn(n) { n = n * 2; n }; f(4);", 8},
+		{
+			`let newCounter = fn() {
+				let count = 0;
+				fn() { count = count + 1; count };
+			};
+			let a = newCounter();
+			let b = newCounter();
+			a(); a(); b();
+			a() * 10 + b();`,
+			32,
+		},
+		{
+			`let pair = fn() {
+				let n = 0;
+				[fn() { n = n + 1 }, fn() { n }]
+			};
+			let p = pair();
+			p[0](); p[0]();
+			p[1]();`,
+			2,
+		},
+		{"let a = [1, 2, 3]; a[1] = 5; a[1];", 5},
+		{"let a = [1, 2, 3]; let b = a; b[0] = 9; a[0];", 9},
+		{"let a = [[1], [2]]; a[1][0] = 7; a[1][0];", 7},
+		{`let m = {"a": 1}; m["a"] = 2; m["b"] = 3; m["a"] + m["b"];`, 5},
+		{"let m = {}; m[true] = 4; m[true];", 4},
+		{"let m = {}; m[2] = 4; m[2.0];", 4},
+		{"let a = [0]; a[0] = 3;", 3},
+		{"let a = [1, 2, 3]; a[-1] = 7; a[2];", 7},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, int64(tc.expected.(int)))
+	}
+}
+
+func TestAssignErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"x = 1;", "assignment to undeclared identifier: x"},
+		{"let f = fn() { y = 1 }; f();", "assignment to undeclared identifier: y"},
+		{"len = 1;", "assignment to undeclared identifier: len"},
+		{"let a = [1]; a[1] = 2;", "index out of range: 1"},
+		{"let a = [1]; a[-2] = 2;", "index out of range: -2"},
+		{`let s = "ab"; s[0] = "c";`, "index assignment not supported: STRING"},
+		{"let m = {}; m[fn(x) { x }] = 1;", "unusable as hash key: FUNCTION"},
+		{"let x = 1; x = 1 + true;", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestWhileLoops(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while (i < 10) { i = i + 1; } i;", 10},
+		{"let i = 0; while (false) { i = i + 1; } i;", 0},
+		{"let i = 0; while (true) { i = i + 1; if (i == 5) { break; } } i;", 5},
+		{
+			`let i = 0; let sum = 0;
+			while (i < 10) {
+				i = i + 1;
+				if (i == 2 * (i / 2)) { continue; }
+				sum = sum + i;
+			}
+			sum;`,
+			25,
+		},
+		{
+			`let i = 0; let count = 0;
+			while (i < 3) {
+				let j = 0;
+				while (true) {
+					if (j == 4) { break; }
+					j = j + 1;
+					count = count + 1;
+				}
+				i = i + 1;
+			}
+			count;`,
+			12,
+		},
+		{
+			`let find = fn(limit) {
+				let i = 0;
+				while (true) {
+					if (i * i > limit) { return i; }
+					i = i + 1;
+				}
+			};
+			find(50);`,
+			8,
+		},
+		{"let i = 0; while (i < 100000) { i = i + 1; } i;", 100000},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testIntegerObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestForLoops(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum = sum + x; } sum;", 6},
+		{"let sum = 0; for (x in []) { sum = sum + 1; } sum;", 0},
+		{`let s = ""; for (c in "abc") { s = c + s; } s;`, "cba"},
+		{`let n = 0; for (k in {"a": 1, "b": 2}) { n = n + {"a": 1, "b": 2}[k]; } n;`, 3},
+		{"for (x in [1, 2, 3]) { } x;", 3},
+		{
+			`let sum = 0;
+			for (x in [1, 2, 3, 4, 5, 6]) {
+				if (x == 2) { continue; }
+				if (x == 5) { break; }
+				sum = sum + x;
+			}
+			sum;`,
+			8,
+		},
+		{
+			`let pairs = 0;
+			for (x in [1, 2, 3]) {
+				for (y in [1, 2, 3]) {
+					if (y > x) { break; }
+					pairs = pairs + 1;
+				}
+			}
+			pairs;`,
+			6,
+		},
+		{
+			`let contains = fn(xs, v) {
+				for (x in xs) { if (x == v) { return true; } }
+				false
+			};
+			contains([1, 2, 3], 2);`,
+			true,
+		},
+		{
+			`let r = [];
+			for (x in [1, 2, 3, 4]) {
+				if (x == 1) { continue } else { if (x == 3) { break } }
+				r = push(r, x);
+			}
+			len(r);`,
+			1,
+		},
+		{"for (x in [1]) { x }", nil},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String, got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"for (x in 5) { }", "not iterable: INTEGER"},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"while (1 + true) { }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let i = 0; while (true) { i = i + 1; if (i == 3) { i + false } }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestUndefinedNameInFunction(t *testing.T) {
+	evaluated := testEval("let f = fn() { missing }; f();")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "identifier not found: missing"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"hello world"`
 	evaluated := testEval(input)
@@ -329,6 +945,14 @@ func TestIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -344,6 +968,43 @@ func TestIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][-10:10]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[1, 2, 3, 4][99999999999999999999:]", "[]"},
+		{"[][0:1]", "[]"},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", "[1, 2, 3]"},
+		{`"hello"[1:3]`, "el"},
+		{`"日本語abc"[1:4]`, "本語a"},
+		{`"naïve"[-3:]`, "ïve"},
+		{`"abc"[5:]`, ""},
+		{"let i = 1; [1, 2, 3][i:i + 1]", "[2]"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"hello world" + " goodbye world"`
 	evaluated := testEval(input)
@@ -404,6 +1065,235 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestStringEscapes(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\tb"`, "a\tb"},
+		{`"line\n" + "next"`, "line\nnext"},
+		{`"\"quoted\""`, `"quoted"`},
+		{`"C:\\dir"`, `C:\dir`},
+		{`"caf\u{e9}"`, "café"},
+		{"`no \\n escapes`", `no \n escapes`},
+		{"`two\nlines`", "two\nlines"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String, got=%T (%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tc.expected {
+			t.Errorf("%s: String has wrong value, expected=%q, got=%q", tc.input, tc.expected, str.Value)
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{`len("héllo")`, int64(5)},
+		{`len("日本語")`, int64(3)},
+		{`byte_len("日本語")`, int64(9)},
+		{`byte_len("abc")`, int64(3)},
+		{`"日本語"[1]`, "本"},
+		{`"naïve"[2]`, "ï"},
+		{`"naïve"[4]`, "e"},
+		{`"naïve"[5]`, nil},
+		{`"abc"[-1]`, "c"},
+		{`"naïve"[-3]`, "ï"},
+		{`"abc"[-4]`, nil},
+		{`let s = ""; for (c in "añb") { s = c + s; } s`, "bña"},
+		{`let n = 0; for (c in "日本") { n = n + 1; } n`, int64(2)},
+		{`let café = "☕"; café`, "☕"},
+		{`let π = 3; π * 2`, int64(6)},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%s: object is not String, got=%T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("%s: String has wrong value, expected=%q, got=%q", tc.input, expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	evaluated := testEval(`bytes("aé")`)
+
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array, got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []int64{97, 195, 169}
+	if len(array.Elements) != len(expected) {
+		t.Fatalf("wrong number of elements, expected=%d, got=%d", len(expected), len(array.Elements))
+	}
+
+	for i, value := range expected {
+		testIntegerObject(t, array.Elements[i], value)
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Monkey"; "hello ${name}!"`, "hello Monkey!"},
+		{`let items = [1, 2]; "you have ${len(items)} items"`, "you have 2 items"},
+		{`"${1} + ${2.5} = ${1 + 2.5}"`, "1 + 2.5 = 3.5"},
+		{`"${true} ${if (false) { 1 }} ${[1, "a"]}"`, "true null [1, a]"},
+		{`"${"nested ${1 + 1}"}"`, "nested 2"},
+		{`let m = {"k": "v"}; "${m["k"]}"`, "v"},
+		{`let f = fn(x) { "<${x}>" }; f(f(1))`, "<<1>>"},
+		{`"\${literal} and $5"`, "${literal} and $5"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String, got=%T (%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tc.expected {
+			t.Errorf("%s: String has wrong value, expected=%q, got=%q", tc.input, tc.expected, str.Value)
+		}
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 >= 1", true},
+		{"1 <= 0.5", false},
+		{"99999999999999999999 >= 99999999999999999999", true},
+		{"99999999999999999999 <= 1", false},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" < "b"`, true},
+		{`"b" > "a"`, true},
+		{`"ab" > "a"`, true},
+		{`"B" < "a"`, true},
+		{`"abc" <= "abd"`, true},
+		{`"abc" >= "abc"`, true},
+		{`"" < "a"`, true},
+		{`let s = "mon"; s + "key" == "monkey"`, true},
+		{`"1" == 1`, false},
+		{`1 != "1"`, true},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testBooleanObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"[] == []", true},
+		{"[1, 2, 3] == [1, 2, 3]", true},
+		{"[1, 2, 3] != [1, 2, 3]", false},
+		{"[1, 2, 3] == [1, 2]", false},
+		{"[1, 2, 3] == [3, 2, 1]", false},
+		{"[1, 2] == [1.0, 2.0]", true},
+		{`[["a"], [true]] == [["a"], [true]]`, true},
+		{`[["a"], [true]] == [["a"], [false]]`, false},
+		{"let a = [1]; let b = a; a == b", true},
+		{"[1] == 1", false},
+		{"[1] == {}", false},
+		{"{} == {}", true},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1, "b": 2} == {"a": 1, "b": 3}`, false},
+		{`{"a": 1, "b": 2} == {"a": 1, "c": 2}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{"a": [1, {"b": 2}]} == {"a": [1, {"b": 2}]}`, true},
+		{"let f = fn(x) { x }; [f] == [f]", true},
+		{"[fn(x) { x }] == [fn(x) { x }]", false},
+		{"let a = [1]; let b = push(a, 2); a == b", false},
+		{"let a = [1]; let b = [1]; a[0] = a; b[0] = b; a == b", true},
+		{"let a = [1, 2]; let b = [1, 3]; a[0] = a; b[0] = b; a == b", false},
+		{`let a = {}; let b = {}; a["x"] = [a]; b["x"] = [b]; a == b`, true},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+		testBooleanObject(t, evaluated, tc.expected)
+	}
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"true || false", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"1 && 2", int64(2)},
+		{"0 && 2", int64(2)},
+		{"false || 5", int64(5)},
+		{"5 || false", int64(5)},
+		{"false && 5", false},
+		{"if (false) { 1 } || 7", int64(7)},
+		{"false && 1 + true", false},
+		{"true || 1 + true", true},
+		{"let n = 0; false && (n = 1); n", int64(0)},
+		{"let n = 0; true || (n = 1); n", int64(0)},
+		{"let n = 0; true && (n = 1); n", int64(1)},
+		{"let n = 0; let f = fn() { n = n + 1; true }; f() || f(); f() && f(); n", int64(3)},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -628,6 +1518,444 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestPuts(t *testing.T) {
+	var out bytes.Buffer
+	Output = &out
+	defer func() { Output = os.Stdout }()
+
+	evaluated := testEval(`puts(1, "two", [3]); puts()`)
+	if evaluated != NULL {
+		t.Errorf("puts should return null, got=%s", evaluated.Inspect())
+	}
+
+	expected := "1\ntwo\n[3]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output, expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("日本", "")`, "[日, 本]"},
+		{`split("", ",")`, "[]"},
+		{`join(["a", "b"], ", ")`, "a, b"},
+		{`join([1, true, 2.5], "-")`, "1-true-2.5"},
+		{`join([], ",")`, ""},
+		{`join(split("a b c", " "), "+")`, "a+b+c"},
+		{`trim("  hi \n\t")`, "hi"},
+		{`upper("straße")`, "STRAßE"},
+		{`lower("ÀBC")`, "àbc"},
+		{`contains("monkey", "key")`, "true"},
+		{`contains("monkey", "")`, "true"},
+		{`contains("monkey", "Key")`, "false"},
+		{`starts_with("monkey", "mon")`, "true"},
+		{`starts_with("monkey", "key")`, "false"},
+		{`ends_with("monkey", "key")`, "true"},
+		{`ends_with("monkey", "mon")`, "false"},
+		{`replace("banana", "a", "o")`, "bonono"},
+		{`replace("banana", "x", "o")`, "banana"},
+		{`index_of("banana", "na")`, "2"},
+		{`index_of("日本語", "語")`, "2"},
+		{`index_of("banana", "x")`, "-1"},
+		{`substr("hello", 1, 3)`, "ell"},
+		{`substr("hello", 3)`, "lo"},
+		{`substr("日本語abc", 1, 3)`, "本語a"},
+		{`substr("hello", 3, 10)`, "lo"},
+		{`substr("hello", 10, 2)`, ""},
+		{`substr("hello", 99999999999999999999)`, ""},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`format("{} + {} = {}", 1, 2.5, 3.5)`, "1 + 2.5 = 3.5"},
+		{`format("{{{}}} {}", [1], "x")`, "{[1]} x"},
+		{`format("plain")`, "plain"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestStringBuiltinErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`split("a")`, "wrong number of arguments. got=1, want=2"},
+		{`split(1, ",")`, "argument 1 to `split` must be STRING, got INTEGER"},
+		{`join(["a"], 1)`, "argument 2 to `join` must be STRING, got INTEGER"},
+		{`trim(1)`, "argument to `trim` must be STRING, got INTEGER"},
+		{`upper("a", "b")`, "wrong number of arguments. got=2, want=1"},
+		{`lower([])`, "argument to `lower` must be STRING, got ARRAY"},
+		{`contains(["a"], "a")`, "argument 1 to `contains` must be STRING, got ARRAY"},
+		{`starts_with("a", 1)`, "argument 2 to `starts_with` must be STRING, got INTEGER"},
+		{`ends_with("a")`, "wrong number of arguments. got=1, want=2"},
+		{`replace("a", "b")`, "wrong number of arguments. got=2, want=3"},
+		{`index_of("a", true)`, "argument 2 to `index_of` must be STRING, got BOOLEAN"},
+		{`substr("a")`, "wrong number of arguments. got=1, want=2 or 3"},
+		{`substr("a", "b")`, "argument 2 to `substr` must be INTEGER, got STRING"},
+		{`substr("abc", -1)`, "start of `substr` must not be negative, got -1"},
+		{`substr("abc", 0, -1)`, "length of `substr` must not be negative, got -1"},
+		{`repeat("a", -1)`, "count of `repeat` must not be negative, got -1"},
+		{`repeat("a", 1.5)`, "argument 2 to `repeat` must be INTEGER, got FLOAT"},
+		{`repeat("ab", 9999999999)`, "result of `repeat` is too long"},
+		{`format()`, "wrong number of arguments. got=0, want at least 1"},
+		{`format(1)`, "argument 1 to `format` must be STRING, got INTEGER"},
+		{`format("{} {}", 1)`, "not enough arguments to `format`, got 1"},
+		{`format("{}", 1, 2)`, "too many arguments to `format`, 1 of 2 used"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, got=%T(%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", tc.input, tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`let k = 10; map([1, 2], fn(x) { x + k })`, "[11, 12]"},
+		{`map(["a", "bc"], len)`, "[1, 2]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })`, "[2, 4]"},
+		{`filter([1, 0, "", "a"], fn(x) { x })`, "[1, 0, , a]"},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x })`, "10"},
+		{`reduce([1, 2, 3], fn(acc, x) { push(acc, x * x) }, [])`, "[1, 4, 9]"},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, "0"},
+		{`reduce([5], fn(acc, x) { acc + x })`, "5"},
+		{`sort([3, 1.5, 2, -1])`, "[-1, 1.5, 2, 3]"},
+		{`sort(["pear", "apple", "fig"])`, "[apple, fig, pear]"},
+		{`let xs = [2, 1]; sort(xs); xs`, "[2, 1]"},
+		{`sort_by(["ccc", "a", "bb"], len)`, "[a, bb, ccc]"},
+		{`sort_by([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], fn(p) { p[0] })`, "[[1, b], [1, d], [2, a], [2, c]]"},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`all([], fn(x) { false })`, "true"},
+		{`any([1, "a"], fn(x) { x == 1 })`, "true"},
+		{`all([0, "a"], fn(x) { x > 0 })`, "false"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip([], [1])`, "[]"},
+		{`range(4)`, "[0, 1, 2, 3]"},
+		{`range(2, 5)`, "[2, 3, 4]"},
+		{`range(10, 0, -3)`, "[10, 7, 4, 1]"},
+		{`range(0, 10, 4)`, "[0, 4, 8]"},
+		{`range(5, 2)`, "[]"},
+		{`range(-2)`, "[]"},
+		{`range(9223372036854775800, 9223372036854775807, 4)`, "[9223372036854775800, 9223372036854775804]"},
+		{`reverse([1, 2, 3])`, "[3, 2, 1]"},
+		{`reverse("héllo")`, "olléh"},
+		{`reverse([])`, "[]"},
+		{`flatten([[1, 2], 3, [[4]], []])`, "[1, 2, 3, [4]]"},
+		{`unique([1, 2, 1, 3, 2])`, "[1, 2, 3]"},
+		{`unique([1, 1.0, "1", [1], [1], {}, {}])`, "[1, 1, [1], {}]"},
+		{`map(range(3), fn(i) { map(range(i), fn(j) { i * j }) })`, "[[], [0], [0, 2]]"},
+		{`let f = fn() { map([1, 2], fn(x) { return x * 3; }) }; f()`, "[3, 6]"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHigherOrderBuiltinErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`map([1])`, "wrong number of arguments. got=1, want=2"},
+		{`map(1, len)`, "argument 1 to `map` must be ARRAY, got INTEGER"},
+		{`map([1], 1)`, "argument 2 to `map` must be FUNCTION, got INTEGER"},
+		{`map([1, "a"], fn(x) { x + 1 })`, "type mismatch: STRING + INTEGER"},
+		{`map([1], fn(x, y) { x })`, "wrong number of arguments. got=1, want=2"},
+		{`filter([1], fn(x) { -true })`, "unknown operator: -BOOLEAN"},
+		{`reduce([], fn(acc, x) { acc })`, "`reduce` of an empty array needs an initial value"},
+		{`reduce([1], fn(acc, x) { acc }, 0, 1)`, "wrong number of arguments. got=4, want=2 or 3"},
+		{`reduce([1, 2], fn(acc, x) { acc + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`sort([1, "a"])`, "type mismatch: INTEGER < STRING"},
+		{`sort([2, 1, "a", 3])`, "type mismatch: INTEGER < STRING"},
+		{`sort([[1], [2]])`, "unknown operator: ARRAY < ARRAY"},
+		{`sort_by([1, 2], fn(x) { if (x == 1) { "a" } else { 1 } })`, "type mismatch: STRING < INTEGER"},
+		{`any([1], fn(x) { len(x) })`, "argument to `len` not supported. got INTEGER"},
+		{`all([1], fn(x) { x / 0 })`, "division by zero"},
+		{`zip([1])`, "wrong number of arguments. got=1, want=2"},
+		{`range()`, "wrong number of arguments. got=0, want=1 to 3"},
+		{`range(1, "a")`, "argument 2 to `range` must be INTEGER, got STRING"},
+		{`range(0, 10, 0)`, "step of `range` must not be zero"},
+		{`range(99999999999)`, "result of `range` is too long"},
+		{`reverse(1)`, "argument to `reverse` not supported. got INTEGER"},
+		{`flatten("a")`, "argument to `flatten` must be ARRAY, got STRING"},
+		{`unique([1], [2])`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, got=%T(%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", tc.input, tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestMapBuiltins(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: true}`, "{b: 1, a: 2, 3: true}"},
+		{`let m = {"b": 1}; m["a"] = 2; m["b"] = 3; m`, "{b: 3, a: 2}"},
+		{`{1: "a", 1.0: "b"}`, "{1.0: b}"},
+		{`len({"a": 1, "b": 2})`, "2"},
+		{`len({})`, "0"},
+		{`keys({"b": 1, "a": 2})`, "[b, a]"},
+		{`values({"b": 1, "a": 2})`, "[1, 2]"},
+		{`entries({"b": 1, "a": [2]})`, "[[b, 1], [a, [2]]]"},
+		{`entries({})`, "[]"},
+		{`has({"a": 1}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`has({1: 1}, 1.0)`, "true"},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, "{a: 1, c: 3}"},
+		{`delete({"a": 1}, "z")`, "{a: 1}"},
+		{`let m = {"a": 1}; delete(m, "a"); m`, "{a: 1}"},
+		{`merge({"a": 1, "b": 2}, {"b": 3, "c": 4})`, "{a: 1, b: 3, c: 4}"},
+		{`let m = {"a": 1}; merge(m, {"b": 2}); m`, "{a: 1}"},
+		{`let out = []; for (k in {"z": 1, "y": 2, "x": 3}) { out = push(out, k) }; out`, "[z, y, x]"},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, "true"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMapBuiltinErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`keys([1])`, "argument to `keys` must be MAP, got ARRAY"},
+		{`values({}, {})`, "wrong number of arguments. got=2, want=1"},
+		{`entries("a")`, "argument to `entries` must be MAP, got STRING"},
+		{`has({})`, "wrong number of arguments. got=1, want=2"},
+		{`has([1], 1)`, "argument 1 to `has` must be MAP or SET, got ARRAY"},
+		{`has({}, [1])`, "unusable as hash key: ARRAY"},
+		{`delete({}, fn(x) { x })`, "unusable as hash key: FUNCTION"},
+		{`merge({}, [])`, "argument 2 to `merge` must be MAP, got ARRAY"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, got=%T(%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", tc.input, tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestTuplesAndSets(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(1, \"a\", (2, 3))", "(1, a, (2, 3))"},
+		{"(1,)", "(1,)"},
+		{"()", "()"},
+		{"(1)", "1"},
+		{"let t = (1, 2, 3); [t[0], t[-1], t[3], len(t)]", "[1, 3, null, 3]"},
+		{"(1, 2, 3)[1:]", "(2, 3)"},
+		{"(1, [2]) == (1, [2])", "true"},
+		{"(1, 2) == [1, 2]", "false"},
+		{"(1, 2) != (2, 1)", "true"},
+		{"{3, 1, 2, 1}", "{3, 1, 2}"},
+		{"{1, 1.0}", "{1}"},
+		{"len({1, 2, 2})", "2"},
+		{"{1, 2} | {2, 3}", "{1, 2, 3}"},
+		{"{1, 2, 3} & {3, 2, 9}", "{2, 3}"},
+		{"{1, 2, 3} - {2}", "{1, 3}"},
+		{"{1} & {2}", "set()"},
+		{"{1, 2} == {2, 1}", "true"},
+		{"{1, 2} != {1}", "true"},
+		{"{(1, 2), {3}}", "{(1, 2), {3}}"},
+		{"set()", "set()"},
+		{`set([1, 2, 1])`, "{1, 2}"},
+		{`set("abca")`, "{a, b, c}"},
+		{`set({"x": 1, "y": 2})`, "{x, y}"},
+		{"tuple([1, 2])", "(1, 2)"},
+		{"tuple({2, 1})", "(2, 1)"},
+		{"[has({1, 2}, 2), has({1, 2}, 3), has({(1, 2)}, (1, 2))]", "[true, false, true]"},
+		{`let m = {(1, 2): "point"}; m[(1, 2)]`, "point"},
+		{`let m = {}; m[{1, 2}] = "set"; m[{2, 1}]`, "set"},
+		{"let n = 0; for (x in (1, 2, 3)) { n = n + x }; n", "6"},
+		{"let out = []; for (x in {3, 1}) { out = push(out, x) }; out", "[3, 1]"},
+		{"unique([(1, 2), (1, 2), ([1],), ([1],)])", "[(1, 2), ([1],)]"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTupleAndSetErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"{1, [2]}", "unusable as set element: ARRAY"},
+		{"{(1, [2])}", "unusable as set element: TUPLE"},
+		{"{([1],): 1}", "unusable as hash key: TUPLE"},
+		{"{1: 2}[([1],)]", "unusable as hash key: TUPLE"},
+		{"let t = (1, 2); t[0] = 3;", "index assignment not supported: TUPLE"},
+		{"{1} + {2}", "unknown operator: SET + SET"},
+		{"{1} | [2]", "type mismatch: SET | ARRAY"},
+		{"1 & 2", "unknown operator: INTEGER & INTEGER"},
+		{"(1, 2) - (1,)", "unknown operator: TUPLE - TUPLE"},
+		{"set(1)", "argument to `set` not supported. got INTEGER"},
+		{"set([1], [2])", "wrong number of arguments. got=2, want=0 or 1"},
+		{"set([[1]])", "unusable as set element: ARRAY"},
+		{"tuple(1)", "argument to `tuple` not supported. got INTEGER"},
+		{"has([1], 1)", "argument 1 to `has` must be MAP or SET, got ARRAY"},
+		{"has({1}, [1])", "unusable as hash key: ARRAY"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, got=%T(%+v)", tc.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tc.expectedMessage {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", tc.input, tc.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestNullAndOptionalChaining(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"null == null", "true"},
+		{"[1][5] == null", "true"},
+		{"null != 0", "true"},
+		{"!null", "true"},
+		{"null ?? 1", "1"},
+		{"0 ?? 1", "0"},
+		{"false ?? 1", "false"},
+		{`"" ?? 1`, ""},
+		{"null ?? null ?? 3", "3"},
+		{"let n = 0; let f = fn() { n = n + 1 }; 5 ?? f(); n", "0"},
+		{`let m = {"name": "ann"}; m?.name`, "ann"},
+		{`let m = {"a": {"b": 2}}; m?.a?.b`, "2"},
+		{`let m = {"a": null}; m?.a?.b`, "null"},
+		{`let m = {}; m?.missing ?? "none"`, "none"},
+		{"let x = null; x?[0]", "null"},
+		{"let x = null; x?[1:]", "null"},
+		{"let x = null; x?.a?.b?[0]", "null"},
+		{"let x = null; x?[1 / 0]", "null"},
+		{"[1, 2, 3]?[-1]", "3"},
+		{"[1, 2, 3]?[1:]", "[2, 3]"},
+		{`{"a": [1, 2]}?.a?[0]`, "1"},
+		{`null?["a"]["b"]`, "null"},
+		{"let x = null; x?.a[0][1:]", "null"},
+		{`let x = null; x?.a[0] ?? "none"`, "none"},
+		{"let x = null; x?.f(1 / 0)", "null"},
+		{`let m = {"a": null}; m?.a?[0]["b"]`, "null"},
+		{`let m = {"f": fn(x) { x * 2 }}; m?.f(2)`, "4"},
+		{"let m = null; (m?[0])?[1]", "null"},
+		{"let a = [[1, 2]]; (a[0])[1] = 3; a", "[[1, 3]]"},
+		{"let f = fn() { [fn(x) { x }] }; (f()[0])(5)", "5"},
+		{"let f = fn(x) { x ?? \"default\" }; [f(null), f(1)]", "[default, 1]"},
+		{"let f = fn() {}; f() ?? 2", "2"},
+		{"let f = fn() {}; [f() == null, [f()]]", "[true, [null]]"},
+		{"let f = fn() { let x = 1; }; f()", "null"},
+		{"if (true) { let x = 1 }", "null"},
+		{"if (false) { 1 } else {}", "null"},
+		{`"${if (true) { let x = 1 }}"`, "null"},
+		{`join([if (true) { let q = 1 }], ",")`, "null"},
+	}
+
+	for _, tc := range testCases {
+		evaluated := testEval(tc.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			t.Errorf("%s: unexpected error: %s", tc.input, errObj.Message)
+			continue
+		}
+
+		if evaluated.Inspect() != tc.expected {
+			t.Errorf("%s: wrong result, expected=%q, got=%q", tc.input, tc.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	testCases := []struct {
 		input           string
@@ -641,6 +1969,106 @@ func TestErrorHandling(t *testing.T) {
 			input:           "5 + true; 5;",
 			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			input:           "true && 1 + true",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           "(1 + true) || true",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           `"a" <= 1`,
+			expectedMessage: "type mismatch: STRING <= INTEGER",
+		},
+		{
+			input:           "[1] >= [1]",
+			expectedMessage: "unknown operator: ARRAY >= ARRAY",
+		},
+		{
+			input:           `"a ${1 + true} b"`,
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           "bytes(1)",
+			expectedMessage: "argument to `bytes` must be STRING, got INTEGER",
+		},
+		{
+			input:           `byte_len("a", "b")`,
+			expectedMessage: "wrong number of arguments. got=2, want=1",
+		},
+		{
+			input:           `"abc"["a"]`,
+			expectedMessage: "index operator not supported: STRING",
+		},
+		{
+			input:           `[1, 2]["a":]`,
+			expectedMessage: "slice index must be INTEGER, got STRING",
+		},
+		{
+			input:           `"abc"[0:1.5]`,
+			expectedMessage: "slice index must be INTEGER, got FLOAT",
+		},
+		{
+			input:           "null[0]",
+			expectedMessage: "index operator not supported: NULL",
+		},
+		{
+			input:           `let m = {"a": null}; m?.a[0]`,
+			expectedMessage: "index operator not supported: NULL",
+		},
+		{
+			input:           "let m = null; (m?[0])[1]",
+			expectedMessage: "index operator not supported: NULL",
+		},
+		{
+			input:           "1?[0]",
+			expectedMessage: "index operator not supported: INTEGER",
+		},
+		{
+			input:           "null ?? 1 + true",
+			expectedMessage: "type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			input:           `{"a": 1}[0:1]`,
+			expectedMessage: "slice operator not supported: MAP",
+		},
+		{
+			input:           `[1][undefined:]`,
+			expectedMessage: "identifier not found: undefined",
+		},
+		{
+			input:           "1 / 0",
+			expectedMessage: "division by zero",
+		},
+		{
+			input:           "1 % 0",
+			expectedMessage: "modulo by zero",
+		},
+		{
+			input:           "99999999999999999999 / 0",
+			expectedMessage: "division by zero",
+		},
+		{
+			input:           "99999999999999999999 % 0",
+			expectedMessage: "modulo by zero",
+		},
+		{
+			input:           "1.5 / 0",
+			expectedMessage: "division by zero",
+		},
+		{
+			input:           "1 % 0.0",
+			expectedMessage: "modulo by zero",
+		},
+		{
+			input:           "let f = fn(x) { 10 / x }; f(0) + 1",
+			expectedMessage: "division by zero",
+		},
+		{
+			input:           `"a" % "b"`,
+			expectedMessage: "unknown operator: STRING % STRING",
+		},
 		{
 			input:           "-true",
 			expectedMessage: "unknown operator: -BOOLEAN",
@@ -677,6 +2105,22 @@ func TestErrorHandling(t *testing.T) {
 			input: `{"name": "Monkey"}[fn(x) { x }]`,
 			expectedMessage: "unusable as hash key: FUNCTION",
 		},
+		{
+			input:           "fn(a, b) { a + b }(1)",
+			expectedMessage: "wrong number of arguments. got=1, want=2",
+		},
+		{
+			input:           "1.5 + true",
+			expectedMessage: "type mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			input:           `1.5 + "a"`,
+			expectedMessage: "type mismatch: FLOAT + STRING",
+		},
+		{
+			input:           "-true + 1.5",
+			expectedMessage: "unknown operator: -BOOLEAN",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -693,8 +2137,49 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) {
+	x + true
+};
+let outer = fn(f, y) { f(y) };
+outer(inner, 1);`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Pos.String() != "2:2" {
+		t.Errorf("wrong error position. expected=%s, got=%s", "2:2", errObj.Pos)
+	}
+
+	expectedStack := []struct {
+		function string
+		pos      string
+	}{
+		{"inner", "4:24"},
+		{"outer", "5:1"},
+	}
+
+	if len(errObj.Stack) != len(expectedStack) {
+		t.Fatalf("wrong stack size. expected=%d, got=%d", len(expectedStack), len(errObj.Stack))
+	}
+
+	for idx, expected := range expectedStack {
+		frame := errObj.Stack[idx]
+		if frame.Function != expected.function {
+			t.Errorf("[%d] wrong function name. expected=%q, got=%q", idx, expected.function, frame.Function)
+		}
+
+		if frame.Pos.String() != expected.pos {
+			t.Errorf("[%d] wrong call site. expected=%s, got=%s", idx, expected.pos, frame.Pos)
+		}
+	}
+}
+
 func testEval(input string) object.Object {
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -717,6 +2202,21 @@ func testIntegerObject(t *testing.T, obj object.Object, val int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, val float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("obj should be of type object.Float, got=%T (%+v)\n", obj, obj)
+		return false
+	}
+
+	if result.Value != val {
+		t.Errorf("obj value should be %g, got=%g\n", val, result.Value)
+		return false
+	}
+
+	return true
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	_, ok := obj.(*object.Null)
 	if !ok {
@@ -741,3 +2241,20 @@ func testBooleanObject(t *testing.T, obj object.Object, val bool) bool {
 
 	return true
 }
+
+func BenchmarkFibonacci(b *testing.B) {
+	input := `
+	let fibonacci = fn(x) {
+		if (x < 2) { return x; }
+		fibonacci(x - 1) + fibonacci(x - 2)
+	};
+	fibonacci(20);`
+
+	l := lexer.New("", input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+func evalBigIntegerInfixExpression(operator string, left, right *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return normalizeInteger(new(big.Int).Add(left, right))
+	case "-":
+		return normalizeInteger(new(big.Int).Sub(left, right))
+	case "*":
+		return normalizeInteger(new(big.Int).Mul(left, right))
+	case "/", "%":
+		if right.Sign() == 0 {
+			if operator == "/" {
+				return newError("division by zero")
+			}
+			return newError("modulo by zero")
+		}
+
+		quo, rem := new(big.Int).QuoRem(left, right, new(big.Int))
+		// QuoRem truncates, adjust toward negative infinity like int64 does
+		if rem.Sign() != 0 && (rem.Sign() < 0) != (right.Sign() < 0) {
+			quo.Sub(quo, big.NewInt(1))
+			rem.Add(rem, right)
+		}
+
+		if operator == "/" {
+			return normalizeInteger(quo)
+		}
+		return normalizeInteger(rem)
+	case ">":
+		return nativeBooleanToObject(left.Cmp(right) > 0)
+	case "<":
+		return nativeBooleanToObject(left.Cmp(right) < 0)
+	case ">=":
+		return nativeBooleanToObject(left.Cmp(right) >= 0)
+	case "<=":
+		return nativeBooleanToObject(left.Cmp(right) <= 0)
+	case "==":
+		return nativeBooleanToObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBooleanToObject(left.Cmp(right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, operator, object.INTEGER_OBJ)
+	}
+}
+
+// normalizeInteger returns an Integer when value fits into an int64 and a
+// BigInt otherwise, a BigInt never holds a value an Integer could.
+func normalizeInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+
+	return &object.BigInt{Value: value}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+// floatToInteger truncates value toward zero, it reports false for NaN and
+// infinities which have no integer equivalent.
+func floatToInteger(value float64) (object.Object, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, false
+	}
+
+	if math.Abs(value) < 1<<63 {
+		return &object.Integer{Value: int64(value)}, true
+	}
+
+	integer, _ := big.NewFloat(value).Int(nil)
+	return normalizeInteger(integer), true
+}
+
+// floorDivInt64 divides rounding toward negative infinity, b must not be
+// zero.
+func floorDivInt64(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q -= 1
+	}
+
+	return q
+}
+
+// floorModInt64 returns the remainder of floorDivInt64, it has the sign of b.
+func floorModInt64(a, b int64) int64 {
+	m := a % b
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+
+	return m
+}
+
+// The int64 helpers below report false when the result overflowed.
+
+func addInt64(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+func subInt64(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	c := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return c, false
+	}
+
+	return c, c/b == a
+}
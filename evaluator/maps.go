@@ -0,0 +1,131 @@
+package evaluator
+
+import (
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// The map builtins, they return their results in the insertion order of the
+// map and like push they leave the maps passed to them untouched.
+
+func builtinKeys(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("keys", args, object.MAP_OBJ); err != nil {
+		return err
+	}
+
+	pairs := args[0].(*object.Map).OrderedPairs()
+	keys := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+
+	return &object.Array{Elements: keys}
+}
+
+func builtinValues(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("values", args, object.MAP_OBJ); err != nil {
+		return err
+	}
+
+	pairs := args[0].(*object.Map).OrderedPairs()
+	values := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		values[i] = pair.Value
+	}
+
+	return &object.Array{Elements: values}
+}
+
+// builtinEntries returns the pairs as [key, value] arrays.
+func builtinEntries(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("entries", args, object.MAP_OBJ); err != nil {
+		return err
+	}
+
+	pairs := args[0].(*object.Map).OrderedPairs()
+	entries := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		entries[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	}
+
+	return &object.Array{Elements: entries}
+}
+
+// builtinHas reports whether a map has the key, or a set the element.
+func builtinHas(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	if args[0].Type() != object.MAP_OBJ && args[0].Type() != object.SET_OBJ {
+		return newError("argument 1 to `has` must be MAP or SET, got %s", args[0].Type())
+	}
+
+	key, ok := object.HashKeyOf(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	if set, ok := args[0].(*object.Set); ok {
+		return nativeBooleanToObject(set.Has(key))
+	}
+
+	_, ok = args[0].(*object.Map).Pairs[key]
+	return nativeBooleanToObject(ok)
+}
+
+// builtinDelete returns a copy of the map without the key.
+func builtinDelete(_ object.Caller, args ...object.Object) object.Object {
+	m, key, err := mapAndKey("delete", args)
+	if err != nil {
+		return err
+	}
+
+	result := copyMap(m)
+	result.Delete(key)
+
+	return result
+}
+
+// builtinMerge returns a map with the pairs of both maps, where a key is in
+// both the value of the second one wins.
+func builtinMerge(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("merge", args, object.MAP_OBJ, object.MAP_OBJ); err != nil {
+		return err
+	}
+
+	result := copyMap(args[0].(*object.Map))
+	other := args[1].(*object.Map)
+	for _, key := range other.Keys {
+		result.Set(key, other.Pairs[key])
+	}
+
+	return result
+}
+
+// mapAndKey checks the arguments of the builtins taking a map and a key.
+func mapAndKey(name string, args []object.Object) (*object.Map, object.HashKey, *object.Error) {
+	if len(args) != 2 {
+		return nil, object.HashKey{}, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	m, ok := args[0].(*object.Map)
+	if !ok {
+		return nil, object.HashKey{}, newError("argument 1 to `%s` must be MAP, got %s", name, args[0].Type())
+	}
+
+	key, ok := object.HashKeyOf(args[1])
+	if !ok {
+		return nil, object.HashKey{}, newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	return m, key, nil
+}
+
+func copyMap(m *object.Map) *object.Map {
+	result := object.NewMap()
+	for _, key := range m.Keys {
+		result.Set(key, m.Pairs[key])
+	}
+
+	return result
+}
@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// newSet builds a set of the elements, duplicates are dropped.
+func newSet(elements []object.Object) object.Object {
+	set := object.NewSet()
+	for _, element := range elements {
+		key, ok := object.HashKeyOf(element)
+		if !ok {
+			return newError("unusable as set element: %s", element.Type())
+		}
+
+		set.Add(key, element)
+	}
+
+	return set
+}
+
+// evalSetInfixExpression implements union with |, intersection with & and
+// difference with -. The results keep the order of the left set, followed by
+// the elements only the right one has for a union.
+func evalSetInfixExpression(operator string, left, right object.Object) object.Object {
+	leftSet := left.(*object.Set)
+	rightSet := right.(*object.Set)
+
+	switch operator {
+	case "|":
+		result := object.NewSet()
+		for _, set := range []*object.Set{leftSet, rightSet} {
+			for _, key := range set.Keys {
+				result.Add(key, set.Elements[key])
+			}
+		}
+
+		return result
+	case "&", "-":
+		// & keeps the elements that are in the right set, - the others
+		keep := operator == "&"
+
+		result := object.NewSet()
+		for _, key := range leftSet.Keys {
+			if rightSet.Has(key) == keep {
+				result.Add(key, leftSet.Elements[key])
+			}
+		}
+
+		return result
+	case "==":
+		return nativeBooleanToObject(objectsEqual(left, right))
+	case "!=":
+		return nativeBooleanToObject(!objectsEqual(left, right))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// builtinSet returns the set of the values a for loop would visit, or the
+// empty set without an argument.
+func builtinSet(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	if len(args) == 0 {
+		return object.NewSet()
+	}
+
+	elements, err := iterableElements(args[0])
+	if err != nil {
+		return newError("argument to `set` not supported. got %s", args[0].Type())
+	}
+
+	return newSet(elements)
+}
+
+// builtinTuple returns a tuple of the values a for loop would visit.
+func builtinTuple(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	elements, err := iterableElements(args[0])
+	if err != nil {
+		return newError("argument to `tuple` not supported. got %s", args[0].Type())
+	}
+
+	// iterating an array or tuple returns its own elements
+	copied := make([]object.Object, len(elements))
+	copy(copied, elements)
+
+	return &object.Tuple{Elements: copied}
+}
@@ -0,0 +1,78 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// The functions in this file expose the evaluator's operator semantics so the
+// vm produces exactly the same values and errors as the tree-walking backend.
+
+func EvalInfix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+func EvalPrefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+func EvalIndex(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// EvalSlice slices left, a bound that was left out is passed as NULL.
+func EvalSlice(left, low, high object.Object) object.Object {
+	return evalSliceExpression(left, low, high)
+}
+
+func EvalIndexAssign(left, index, value object.Object) object.Object {
+	return evalIndexAssignment(left, index, value)
+}
+
+// IterableElements returns the values a for loop visits, or an error when
+// obj cannot be iterated over.
+func IterableElements(obj object.Object) ([]object.Object, *object.Error) {
+	return iterableElements(obj)
+}
+
+// NewSet builds a set of the elements, or returns an error when one of them
+// is not hashable.
+func NewSet(elements []object.Object) object.Object {
+	return newSet(elements)
+}
+
+func Interpolate(parts []object.Object) *object.String {
+	return interpolate(parts)
+}
+
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+func NewError(format string, a ...any) *object.Error {
+	return newError(format, a...)
+}
+
+var builtinNames = sortedBuiltinNames()
+
+func sortedBuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// BuiltinNames returns the names of all builtins in a stable order, the
+// compiler refers to builtins by their index in it.
+func BuiltinNames() []string {
+	return builtinNames
+}
+
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
@@ -0,0 +1,219 @@
+package evaluator
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// The string builtins, positions and lengths they take or return count runes
+// like indexing and len do.
+
+func builtinSplit(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("split", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	parts := strings.Split(args[0].(*object.String).Value, args[1].(*object.String).Value)
+
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// builtinJoin formats the elements like string interpolation does, so they
+// need not be strings.
+func builtinJoin(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("join", args, object.ARRAY_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	elements := args[0].(*object.Array).Elements
+	parts := make([]string, len(elements))
+	for i, element := range elements {
+		parts[i] = element.Inspect()
+	}
+
+	return &object.String{Value: strings.Join(parts, args[1].(*object.String).Value)}
+}
+
+func builtinTrim(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("trim", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.TrimSpace(args[0].(*object.String).Value)}
+}
+
+func builtinUpper(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("upper", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToUpper(args[0].(*object.String).Value)}
+}
+
+func builtinLower(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("lower", args, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.ToLower(args[0].(*object.String).Value)}
+}
+
+func builtinContains(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("contains", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return nativeBooleanToObject(strings.Contains(args[0].(*object.String).Value, args[1].(*object.String).Value))
+}
+
+func builtinStartsWith(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("starts_with", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return nativeBooleanToObject(strings.HasPrefix(args[0].(*object.String).Value, args[1].(*object.String).Value))
+}
+
+func builtinEndsWith(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("ends_with", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	return nativeBooleanToObject(strings.HasSuffix(args[0].(*object.String).Value, args[1].(*object.String).Value))
+}
+
+func builtinReplace(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("replace", args, object.STRING_OBJ, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String).Value
+	old := args[1].(*object.String).Value
+	replacement := args[2].(*object.String).Value
+
+	return &object.String{Value: strings.ReplaceAll(str, old, replacement)}
+}
+
+// builtinIndexOf returns the position of the first occurrence of the
+// substring, or -1 when there is none.
+func builtinIndexOf(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("index_of", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+		return err
+	}
+
+	str := args[0].(*object.String).Value
+	idx := strings.Index(str, args[1].(*object.String).Value)
+	if idx < 0 {
+		return &object.Integer{Value: -1}
+	}
+
+	return &object.Integer{Value: int64(utf8.RuneCountInString(str[:idx]))}
+}
+
+// builtinSubstr returns length characters from start on, or all of them
+// when length is left out. The result is cut short at the end of the string.
+func builtinSubstr(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	types := []object.ObjectType{object.STRING_OBJ, object.INTEGER_OBJ, object.INTEGER_OBJ}
+	if err := checkArgs("substr", args, types[:len(args)]...); err != nil {
+		return err
+	}
+
+	chars := []rune(args[0].(*object.String).Value)
+	size := int64(len(chars))
+
+	from := saturatedInt64(args[1])
+	if from < 0 {
+		return newError("start of `substr` must not be negative, got %s", args[1].Inspect())
+	}
+	if from > size {
+		from = size
+	}
+
+	to := size
+	if len(args) == 3 {
+		length := saturatedInt64(args[2])
+		if length < 0 {
+			return newError("length of `substr` must not be negative, got %s", args[2].Inspect())
+		}
+		if length < size-from {
+			to = from + length
+		}
+	}
+
+	return &object.String{Value: string(chars[from:to])}
+}
+
+func builtinRepeat(_ object.Caller, args ...object.Object) object.Object {
+	if err := checkArgs("repeat", args, object.STRING_OBJ, object.INTEGER_OBJ); err != nil {
+		return err
+	}
+
+	count := saturatedInt64(args[1])
+	if count < 0 {
+		return newError("count of `repeat` must not be negative, got %s", args[1].Inspect())
+	}
+
+	str := args[0].(*object.String).Value
+	if len(str) > 0 && count > maxStringLength/int64(len(str)) {
+		return newError("result of `repeat` is too long")
+	}
+
+	return &object.String{Value: strings.Repeat(str, int(count))}
+}
+
+// maxStringLength caps the strings repeat builds, in bytes.
+const maxStringLength = 1 << 30
+
+// builtinFormat replaces each {} in the format string with the next
+// argument, formatted like string interpolation does. {{ and }} stand for
+// literal braces.
+func builtinFormat(_ object.Caller, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	format, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument 1 to `format` must be STRING, got %s", args[0].Type())
+	}
+
+	var out strings.Builder
+	values := args[1:]
+	used := 0
+
+	str := format.Value
+	for i := 0; i < len(str); i++ {
+		switch {
+		case strings.HasPrefix(str[i:], "{{"), strings.HasPrefix(str[i:], "}}"):
+			out.WriteByte(str[i])
+			i += 1
+		case strings.HasPrefix(str[i:], "{}"):
+			if used == len(values) {
+				return newError("not enough arguments to `format`, got %d", len(values))
+			}
+
+			out.WriteString(values[used].Inspect())
+			used += 1
+			i += 1
+		default:
+			out.WriteByte(str[i])
+		}
+	}
+
+	if used != len(values) {
+		return newError("too many arguments to `format`, %d of %d used", used, len(values))
+	}
+
+	return &object.String{Value: out.String()}
+}
@@ -1,38 +1,66 @@
 package lexer
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/aryuuu/gonkey-lang/token"
 )
 
+// Literals of the ERROR tokens produced for a string or a block comment the
+// input ends in.
+const (
+	UnterminatedString  = "unterminated string"
+	UnterminatedComment = "unterminated comment"
+)
+
 type Lexer struct {
+	file         string
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	position     int  // current byte offset in input (points to current char)
+	readPosition int  // current reading byte offset in input (after current char)
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char, counted in runes
+
+	// interpolations holds, for every ${ the lexer is inside of, the number
+	// of braces opened since, the } closing it resumes the string
+	interpolations []int
 }
 
-func New(input string) *Lexer {
-	l := &Lexer{input: input}
+func New(file string, input string) *Lexer {
+	l := &Lexer{file: file, input: input, line: 1}
 	l.readChar()
 
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // EOF
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+	l.column += 1
 }
 
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	start := l.currentPosition()
 
 	switch l.ch {
 	case '=':
@@ -44,6 +72,34 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.PIPE, l.ch)
+		}
+	case '?':
+		switch l.peekChar() {
+		case '?':
+			l.readChar()
+			tok = token.Token{Type: token.NULLISH, Literal: "??"}
+		case '.':
+			l.readChar()
+			tok = token.Token{Type: token.OPTIONAL_DOT, Literal: "?."}
+		case '[':
+			l.readChar()
+			tok = token.Token{Type: token.OPTIONAL_BRACKET, Literal: "?["}
+		default:
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
@@ -64,28 +120,67 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		// skipWhitespace leaves doc comments and unterminated block comments
+		switch l.peekChar() {
+		case '/':
+			tok.Type = token.DOC_COMMENT
+			tok.Literal = l.readDocComment()
+		case '*':
+			for l.peekChar() != 0 {
+				l.readChar()
+			}
+			tok.Type = token.ERROR
+			tok.Literal = UnterminatedComment
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LT_EQ, Literal: "<="}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GT_EQ, Literal: ">="}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '{':
+		if depth := len(l.interpolations); depth > 0 {
+			l.interpolations[depth-1] += 1
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		depth := len(l.interpolations)
+		if depth > 0 && l.interpolations[depth-1] == 0 {
+			l.interpolations = l.interpolations[:depth-1]
+			tok.Literal, tok.Type = l.readString(true)
+			break
+		}
+
+		if depth > 0 {
+			l.interpolations[depth-1] -= 1
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal, tok.Type = l.readString(false)
+	case '`':
+		tok.Literal, tok.Type = l.readRawString()
 	case 0:
 		tok.Type = token.EOF
 		tok.Literal = ""
@@ -93,23 +188,37 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Span = token.Span{Start: start, End: l.currentPosition()}
 			return tok
 		}
 
 		if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Span = token.Span{Start: start, End: l.currentPosition()}
 			return tok
 		}
 
 		tok = newToken(token.ILLEGAL, l.ch)
 	}
 
-	l.readChar()
+	if tok.Type != token.EOF {
+		l.readChar()
+	}
+	tok.Span = token.Span{Start: start, End: l.currentPosition()}
 
 	return tok
 }
 
+// currentPosition returns the source position of the current char.
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		File:   l.file,
+		Line:   l.line,
+		Column: l.column,
+		Offset: l.position,
+	}
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) {
@@ -119,50 +228,249 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// readString reads a double quoted string and decodes its escape sequences.
+// A malformed escape turns the whole string into an ERROR token, the rest of
+// it is still consumed so lexing resumes after the closing quote.
+//
+// The string stops early at a ${, the tokens of the embedded expression
+// follow and the } ending it reads the rest of the string with resumed set.
+func (l *Lexer) readString(resumed bool) (string, token.TokenType) {
+	var out strings.Builder
+	errMsg := ""
 
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+
+		switch l.ch {
+		case 0:
+			return UnterminatedString, token.ERROR
+		case '"':
+			if errMsg != "" {
+				return errMsg, token.ERROR
+			}
+			if resumed {
+				return out.String(), token.STRING_TAIL
+			}
+			return out.String(), token.STRING
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteRune(l.ch)
+				continue
+			}
+
+			l.readChar()
+			l.interpolations = append(l.interpolations, 0)
+
+			if errMsg != "" {
+				return errMsg, token.ERROR
+			}
+			if resumed {
+				return out.String(), token.STRING_MID
+			}
+			return out.String(), token.STRING_HEAD
+		case '\\':
+			l.readChar()
+
+			switch l.ch {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case '"':
+				out.WriteByte('"')
+			case '\\':
+				out.WriteByte('\\')
+			case '$':
+				out.WriteByte('$')
+			case 'u':
+				r, msg := l.readUnicodeEscape()
+				if msg != "" && errMsg == "" {
+					errMsg = msg
+				}
+				out.WriteRune(r)
+			case 0:
+				return UnterminatedString, token.ERROR
+			default:
+				if errMsg == "" {
+					errMsg = fmt.Sprintf("unknown escape sequence \\%c", l.ch)
+				}
+			}
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
+}
 
-	return l.input[position:l.position]
+// readUnicodeEscape reads the `{...}` part of a `\u{...}` escape, one to six
+// hex digits naming a code point. It returns a message when they don't.
+func (l *Lexer) readUnicodeEscape() (rune, string) {
+	if l.peekChar() != '{' {
+		return 0, "invalid unicode escape, expected \\u{...}"
+	}
+	l.readChar()
+
+	position := l.position + 1
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+	}
+	digits := l.input[position : l.position+1]
+
+	if l.peekChar() != '}' || len(digits) == 0 || len(digits) > 6 {
+		return 0, "invalid unicode escape, expected \\u{...}"
+	}
+	l.readChar()
+
+	value, _ := strconv.ParseUint(digits, 16, 32)
+	if !utf8.ValidRune(rune(value)) {
+		return 0, fmt.Sprintf("invalid unicode code point \\u{%s}", digits)
+	}
+
+	return rune(value), ""
 }
 
-func (l *Lexer) readNumber() string {
+// readRawString reads a backtick quoted string, it can span several lines
+// and has no escape sequences.
+func (l *Lexer) readRawString() (string, token.TokenType) {
+	position := l.position + 1
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case 0:
+			return UnterminatedString, token.ERROR
+		case '`':
+			return l.input[position:l.position], token.STRING
+		}
+	}
+}
+
+// readNumber reads an integer or a float literal, a float being digits
+// followed by a fractional part, an exponent or both.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if (l.ch == 'e' || l.ch == 'E') && l.exponentFollows() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
+}
 
-	return l.input[position:l.position]
+// exponentFollows reports whether the `e` at the current char starts an
+// exponent, that is whether it is followed by digits with an optional sign.
+func (l *Lexer) exponentFollows() bool {
+	next := l.peekChar()
+	if next == '+' || next == '-' {
+		if l.readPosition+1 >= len(l.input) {
+			return false
+		}
+		next = rune(l.input[l.readPosition+1])
+	}
+
+	return isDigit(next)
 }
 
+// skipWhitespace skips whitespace along with // line comments and /* */
+// block comments, block comments don't nest.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case strings.HasPrefix(l.rest(), "//") && !l.atDocComment():
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case strings.HasPrefix(l.rest(), "/*"):
+			end := strings.Index(l.input[l.position+2:], "*/")
+			if end < 0 {
+				return
+			}
+
+			stop := l.position + 2 + end + 2
+			for l.position < stop {
+				l.readChar()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// atDocComment reports whether a /// doc comment starts at the current char,
+// four or more slashes make an ordinary comment.
+func (l *Lexer) atDocComment() bool {
+	rest := l.rest()
+	return strings.HasPrefix(rest, "///") && !strings.HasPrefix(rest, "////")
+}
+
+// rest returns the input from the current char on.
+func (l *Lexer) rest() string {
+	if l.position >= len(l.input) {
+		return ""
+	}
+
+	return l.input[l.position:]
+}
+
+// readDocComment reads a /// comment up to the end of its line and returns
+// its text without the slashes and the space following them.
+func (l *Lexer) readDocComment() string {
+	position := l.position + 3
+	for l.peekChar() != '\n' && l.peekChar() != 0 {
 		l.readChar()
 	}
+
+	text := strings.TrimSuffix(l.input[position:l.readPosition], "\r")
+	return strings.TrimPrefix(text, " ")
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	}
 
-	return l.input[l.readPosition]
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter reports whether ch can be part of an identifier, any Unicode
+// letter can.
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
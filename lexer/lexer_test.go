@@ -15,7 +15,7 @@ func TestNextToken(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *%5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -30,6 +30,10 @@ func TestNextToken(t *testing.T) {
 	"foo bar"
 	[1, 2];
 	{"foo": "bar"};
+	while for in break continue
+	a && b || c & d | e
+	1 <= 2 >= 3
+	null ?? a?.b?[0] ?
 	`
 
 	testCases := []struct {
@@ -76,6 +80,7 @@ func TestNextToken(t *testing.T) {
 		{token.MINUS, "-"},
 		{token.SLASH, "/"},
 		{token.ASTERISK, "*"},
+		{token.PERCENT, "%"},
 		{token.INT, "5"},
 		{token.SEMICOLON, ";"},
 		{token.INT, "5"},
@@ -123,10 +128,38 @@ func TestNextToken(t *testing.T) {
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "d"},
+		{token.PIPE, "|"},
+		{token.IDENT, "e"},
+		{token.INT, "1"},
+		{token.LT_EQ, "<="},
+		{token.INT, "2"},
+		{token.GT_EQ, ">="},
+		{token.INT, "3"},
+		{token.NULL, "null"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "a"},
+		{token.OPTIONAL_DOT, "?."},
+		{token.IDENT, "b"},
+		{token.OPTIONAL_BRACKET, "?["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.ILLEGAL, "?"},
 		{token.EOF, ""},
 	}
 
-	l := New(input)
+	l := New("", input)
 
 	for i, tc := range testCases {
 		token := l.NextToken()
@@ -141,3 +174,249 @@ func TestNextToken(t *testing.T) {
 	}
 
 }
+
+func TestNumberLiterals(t *testing.T) {
+	input := `5 3.14 0.5 1e9 1e-9 2.5E+3 7.x 8e 9e+`
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "8"},
+		{token.IDENT, "e"},
+		{token.INT, "9"},
+		{token.IDENT, "e"},
+		{token.PLUS, "+"},
+		{token.EOF, ""},
+	}
+
+	l := New("", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	input := `"a\tb\n" "say \"hi\"" "back\\slash" "\u{e9}\u{1F600}" ` + "`raw \\n\nline`" + ` "bad \q" "\u{110000}" "\u{}" "after" "open`
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "a\tb\n"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, `back\slash`},
+		{token.STRING, "\u00e9\U0001F600"},
+		{token.STRING, "raw \\n\nline"},
+		{token.ERROR, `unknown escape sequence \q`},
+		{token.ERROR, `invalid unicode code point \u{110000}`},
+		{token.ERROR, `invalid unicode escape, expected \u{...}`},
+		{token.STRING, "after"},
+		{token.ERROR, UnterminatedString},
+		{token.EOF, ""},
+	}
+
+	l := New("", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"a ${x} b ${ {"k": "${y}"}["k"] } c" "\${no} $5" "${z`
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING_HEAD, "a "},
+		{token.IDENT, "x"},
+		{token.STRING_MID, " b "},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.STRING_HEAD, ""},
+		{token.IDENT, "y"},
+		{token.STRING_TAIL, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.STRING_TAIL, " c"},
+		{token.STRING, "${no} $5"},
+		{token.STRING_HEAD, ""},
+		{token.IDENT, "z"},
+		{token.EOF, ""},
+	}
+
+	l := New("", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// line comment
+	let x = 1; // trailing
+	/* block
+	   comment */ x /* inline */ / 2
+	/// doc ` + "\r" + `
+	///no space
+	//// not a doc
+	"// not a comment"
+	x /* open`
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.DOC_COMMENT, "doc "},
+		{token.DOC_COMMENT, "no space"},
+		{token.STRING, "// not a comment"},
+		{token.IDENT, "x"},
+		{token.ERROR, UnterminatedComment},
+		{token.EOF, ""},
+	}
+
+	l := New("", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 10;
+  "foo" == x`
+
+	pos := func(line, column, offset int) token.Position {
+		return token.Position{File: "main.gk", Line: line, Column: column, Offset: offset}
+	}
+
+	testCases := []struct {
+		expectedType  token.TokenType
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{token.LET, pos(1, 1, 0), pos(1, 4, 3)},
+		{token.IDENT, pos(1, 5, 4), pos(1, 6, 5)},
+		{token.ASSIGN, pos(1, 7, 6), pos(1, 8, 7)},
+		{token.INT, pos(1, 9, 8), pos(1, 11, 10)},
+		{token.SEMICOLON, pos(1, 11, 10), pos(1, 12, 11)},
+		{token.STRING, pos(2, 3, 14), pos(2, 8, 19)},
+		{token.EQ, pos(2, 9, 20), pos(2, 11, 22)},
+		{token.IDENT, pos(2, 12, 23), pos(2, 13, 24)},
+		{token.EOF, pos(2, 13, 24), pos(2, 13, 24)},
+	}
+
+	l := New("main.gk", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Span.Start != tc.expectedStart {
+			t.Errorf("tests[%d] - wrong start position. expected=%+v, got=%+v", i, tc.expectedStart, tok.Span.Start)
+		}
+
+		if tok.Span.End != tc.expectedEnd {
+			t.Errorf("tests[%d] - wrong end position. expected=%+v, got=%+v", i, tc.expectedEnd, tok.Span.End)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := `let café = "日本"; π ☺`
+
+	pos := func(column, offset int) token.Position {
+		return token.Position{Line: 1, Column: column, Offset: offset}
+	}
+
+	testCases := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedStart   token.Position
+	}{
+		{token.LET, "let", pos(1, 0)},
+		{token.IDENT, "café", pos(5, 4)},
+		{token.ASSIGN, "=", pos(10, 10)},
+		{token.STRING, "日本", pos(12, 12)},
+		{token.SEMICOLON, ";", pos(16, 20)},
+		{token.IDENT, "π", pos(18, 22)},
+		{token.ILLEGAL, "☺", pos(20, 25)},
+		{token.EOF, "", pos(21, 28)},
+	}
+
+	l := New("", input)
+
+	for i, tc := range testCases {
+		tok := l.NextToken()
+
+		if tok.Type != tc.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected=%q, got=%q", i, tc.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Span.Start != tc.expectedStart {
+			t.Errorf("tests[%d] - wrong start position. expected=%+v, got=%+v", i, tc.expectedStart, tok.Span.Start)
+		}
+	}
+}
@@ -1,14 +1,74 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"strings"
 
+	"github.com/aryuuu/gonkey-lang/compiler"
+	"github.com/aryuuu/gonkey-lang/diagnostic"
+	"github.com/aryuuu/gonkey-lang/evaluator"
+	"github.com/aryuuu/gonkey-lang/lexer"
+	"github.com/aryuuu/gonkey-lang/object"
+	"github.com/aryuuu/gonkey-lang/parser"
 	"github.com/aryuuu/gonkey-lang/repl"
+	"github.com/aryuuu/gonkey-lang/vm"
+)
+
+const usage = `Usage:
+  gonkey                              start the interactive REPL
+  gonkey repl                         start the interactive REPL
+  gonkey run [-engine name] <file>    run a script, use - to read it from stdin
+  gonkey eval [-engine name] -e <src> evaluate source and print the result
+
+Engines:
+  eval  tree-walking interpreter (default)
+  vm    bytecode compiler and virtual machine
+`
+
+// engines
+const (
+	engineEval = "eval"
+	engineVM   = "vm"
+)
+
+// exit codes
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
 )
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		return runRepl()
+	}
+
+	switch args[0] {
+	case "repl":
+		return runRepl()
+	case "run":
+		return runFile(args[1:])
+	case "eval":
+		return runEval(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+func runRepl() int {
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,4 +77,130 @@ func main() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n", user.Username)
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
+
+	return exitOK
+}
+
+func runFile(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	engine := flags.String("engine", engineEval, "execution engine, eval or vm")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 || !validEngine(*engine) {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+
+	filename := flags.Arg(0)
+
+	var source []byte
+	var err error
+	if filename == "-" {
+		filename = "<stdin>"
+		source, err = io.ReadAll(os.Stdin)
+	} else {
+		source, err = os.ReadFile(filename)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read script: %s\n", err)
+		return exitError
+	}
+
+	_, code := execute(*engine, filename, stripShebang(string(source)))
+
+	return code
+}
+
+func runEval(args []string) int {
+	flags := flag.NewFlagSet("eval", flag.ContinueOnError)
+	source := flags.String("e", "", "source to evaluate")
+	engine := flags.String("engine", engineEval, "execution engine, eval or vm")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *source == "" || !validEngine(*engine) {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+
+	result, code := execute(*engine, "<eval>", *source)
+	if code == exitOK && result != nil {
+		fmt.Println(result.Inspect())
+	}
+
+	return code
+}
+
+func validEngine(engine string) bool {
+	return engine == engineEval || engine == engineVM
+}
+
+// execute parses and runs source with the given engine, reporting parse,
+// compile and runtime errors to stderr.
+func execute(engine string, filename string, source string) (object.Object, int) {
+	l := lexer.New(filename, source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.GetErrors()) != 0 {
+		diagnostic.RenderAll(os.Stderr, source, p.GetErrors())
+		return nil, exitError
+	}
+
+	var result object.Object
+	if engine == engineVM {
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			fmt.Fprintln(os.Stderr, compileError(err).Trace())
+			return nil, exitError
+		}
+
+		machine := vm.New(comp.Bytecode())
+		if err := machine.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			return nil, exitError
+		}
+
+		result = machine.Result()
+	} else {
+		result = evaluator.Eval(program, object.NewEnvironment())
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, errObj.Trace())
+		return nil, exitError
+	}
+
+	return result, exitOK
+}
+
+// compileError turns err into an error object so it is reported like the
+// runtime ones, at the node the compiler stopped at.
+func compileError(err error) *object.Error {
+	errObj := &object.Error{Message: err.Error()}
+
+	var located *compiler.Error
+	if errors.As(err, &located) {
+		errObj.Pos = located.Pos
+	}
+
+	return errObj
+}
+
+// stripShebang blanks out a leading `#!` line so scripts can be made
+// executable, the newline is kept so positions still match the file.
+func stripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+
+	if idx := strings.IndexByte(source, '\n'); idx >= 0 {
+		return source[idx:]
+	}
+
+	return ""
 }
@@ -1,5 +1,11 @@
 package object
 
+// Environment holds the bindings of one scope. Scopes are lexical: a function
+// remembers the environment it was defined in and every call gets a fresh
+// environment enclosed by it, so the body sees the parameters first, then the
+// bindings visible where the function was written, never those of the caller.
+// A `let` always binds in the current scope, shadowing any outer binding with
+// the same name without touching it.
 func NewEnvironment() *Environment {
 	store := make(map[string]Object)
 	return &Environment{
@@ -13,6 +19,7 @@ type Environment struct {
 	outer *Environment
 }
 
+// Get looks name up in this scope and then in each enclosing scope in turn.
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -21,13 +28,29 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Set binds name in this scope only.
 func (e *Environment) Set(name string, value Object) Object {
 	e.store[name] = value
 	return value
 }
 
+// Assign rebinds name in the nearest scope that declares it. It reports false,
+// binding nothing, when no scope does.
+func (e *Environment) Assign(name string, value Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = value
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, value)
+	}
+
+	return false
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
-	env.outer = env
+	env.outer = outer
 	return env
 }
@@ -4,24 +4,36 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/aryuuu/gonkey-lang/ast"
+	"github.com/aryuuu/gonkey-lang/code"
+	"github.com/aryuuu/gonkey-lang/token"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	MAP_OBJ          = "MAP"
+	TUPLE_OBJ        = "TUPLE"
+	SET_OBJ          = "SET"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
 )
 
 type Object interface {
@@ -48,6 +60,71 @@ func (i *Integer) HashKey() HashKey {
 	}
 }
 
+// BigInt is an integer outside of the int64 range. Integer arithmetic is
+// promoted to a BigInt on overflow and results that fit into an int64 are
+// turned back into an Integer, so to programs both are just INTEGER.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType {
+	return INTEGER_OBJ
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+
+	// a BigInt never equals an Integer, so its keys get their own type
+	return HashKey{
+		Type:  "BIGINT",
+		Value: h.Sum64(),
+	}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
+// Inspect always shows a fractional part or an exponent so a float is never
+// mistaken for an integer.
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(str, ".eIN") {
+		return str
+	}
+
+	return str + ".0"
+}
+
+// HashKey makes a float with an integral value the same key as the equal
+// integer, since the two compare equal.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		if math.Abs(f.Value) < 1<<63 {
+			integer := &Integer{Value: int64(f.Value)}
+			return integer.HashKey()
+		}
+
+		value, _ := big.NewFloat(f.Value).Int(nil)
+		integer := &BigInt{Value: value}
+		return integer.HashKey()
+	}
+
+	return HashKey{
+		Type:  f.Type(),
+		Value: math.Float64bits(f.Value),
+	}
+}
+
 type String struct {
 	Value string
 }
@@ -128,8 +205,16 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+// Map remembers the order its keys were first set in, Inspect and iteration
+// follow it. Pairs must only be changed through Set and Delete so Keys stays
+// in step with it.
+func NewMap() *Map {
+	return &Map{Pairs: make(map[HashKey]HashPair)}
+}
+
 type Map struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey
 }
 
 func (m *Map) Type() ObjectType {
@@ -140,7 +225,7 @@ func (m *Map) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range m.Pairs {
+	for _, pair := range m.OrderedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -151,6 +236,41 @@ func (m *Map) Inspect() string {
 	return out.String()
 }
 
+// Set binds the pair to key, a key that is already present keeps its place.
+func (m *Map) Set(key HashKey, pair HashPair) {
+	if _, ok := m.Pairs[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Pairs[key] = pair
+}
+
+// Delete removes key, it reports whether the key was present.
+func (m *Map) Delete(key HashKey) bool {
+	if _, ok := m.Pairs[key]; !ok {
+		return false
+	}
+	delete(m.Pairs, key)
+
+	for i, k := range m.Keys {
+		if k == key {
+			m.Keys = append(m.Keys[:i:i], m.Keys[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// OrderedPairs returns the pairs in insertion order.
+func (m *Map) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, len(m.Keys))
+	for i, key := range m.Keys {
+		pairs[i] = m.Pairs[key]
+	}
+
+	return pairs
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
@@ -165,7 +285,154 @@ type HashKey struct {
 	Value uint64
 }
 
+// HashKeyOf returns the hash key of obj, it reports false when obj cannot be
+// a map key, which a tuple can only be when all of its elements can.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	if tuple, ok := obj.(*Tuple); ok {
+		for _, element := range tuple.Elements {
+			if _, ok := HashKeyOf(element); !ok {
+				return HashKey{}, false
+			}
+		}
+	}
+
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+
+	return hashable.HashKey(), true
+}
+
+// hashKeys hashes a sequence of hash keys into one.
+func hashKeys(keys []HashKey) uint64 {
+	h := fnv.New64a()
+	for _, key := range keys {
+		h.Write([]byte(key.Type))
+		h.Write([]byte(strconv.FormatUint(key.Value, 16)))
+	}
+
+	return h.Sum64()
+}
+
+// Tuple is an immutable sequence, see HashKeyOf for when it is hashable.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType {
+	return TUPLE_OBJ
+}
+
+// Inspect shows a trailing comma for a single element, like the literal.
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range t.Elements {
+		elements = append(elements, el.Inspect())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// HashKey must only be called on tuples HashKeyOf accepts.
+func (t *Tuple) HashKey() HashKey {
+	keys := make([]HashKey, len(t.Elements))
+	for i, element := range t.Elements {
+		keys[i] = element.(Hashable).HashKey()
+	}
+
+	return HashKey{
+		Type:  t.Type(),
+		Value: hashKeys(keys),
+	}
+}
+
+// Set is an immutable collection of distinct hashable values, it remembers
+// the order they were added in like Map does.
+func NewSet() *Set {
+	return &Set{Elements: make(map[HashKey]Object)}
+}
+
+type Set struct {
+	Elements map[HashKey]Object
+	Keys     []HashKey
+}
+
+func (s *Set) Type() ObjectType {
+	return SET_OBJ
+}
+
+// Inspect shows the empty set as set() since {} is the empty map.
+func (s *Set) Inspect() string {
+	if len(s.Keys) == 0 {
+		return "set()"
+	}
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range s.OrderedElements() {
+		elements = append(elements, el.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// HashKey does not depend on the order of the elements, as equal sets
+// may have been built in different orders.
+func (s *Set) HashKey() HashKey {
+	var sum uint64
+	for _, key := range s.Keys {
+		sum += hashKeys([]HashKey{key})
+	}
+
+	return HashKey{
+		Type:  s.Type(),
+		Value: sum,
+	}
+}
+
+// Add adds obj under key unless it is already there, it is only meant for
+// building a set.
+func (s *Set) Add(key HashKey, obj Object) {
+	if _, ok := s.Elements[key]; ok {
+		return
+	}
+
+	s.Keys = append(s.Keys, key)
+	s.Elements[key] = obj
+}
+
+func (s *Set) Has(key HashKey) bool {
+	_, ok := s.Elements[key]
+	return ok
+}
+
+// OrderedElements returns the elements in the order they were added in.
+func (s *Set) OrderedElements() []Object {
+	elements := make([]Object, len(s.Keys))
+	for i, key := range s.Keys {
+		elements[i] = s.Elements[key]
+	}
+
+	return elements
+}
+
 type Function struct {
+	Name       string // name of the variable the function was first bound to, if any
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -176,10 +443,16 @@ func (f *Function) Type() ObjectType {
 }
 
 func (f *Function) Inspect() string {
+	return functionSource(f.Parameters, f.Body)
+}
+
+// functionSource formats a function from its parameters and body, it is how
+// functions of either backend inspect.
+func functionSource(parameters []*ast.Identifier, body *ast.BlockStatement) string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Parameters {
+	for _, p := range parameters {
 		params = append(params, p.String())
 	}
 
@@ -188,7 +461,7 @@ func (f *Function) Inspect() string {
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
 	out.WriteString("{\n")
-	out.WriteString(f.Body.String())
+	out.WriteString(body.String())
 	out.WriteString("\n}")
 
 	return out.String()
@@ -206,8 +479,42 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// Break and Continue are signals, like ReturnValue, that unwind the
+// statements of a loop body up to the loop evaluating it.
+type Break struct{}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
 type Error struct {
 	Message string
+	Pos     token.Position // where the error was raised
+	Stack   []Frame        // calls the error propagated through, innermost first
+}
+
+// maxTraceFrames caps the number of frames printed by Trace, deep recursion
+// would otherwise bury the message under thousands of identical lines.
+const maxTraceFrames = 32
+
+// Frame is a single function call in the stack trace of an Error.
+type Frame struct {
+	Function string         // empty for anonymous functions
+	Pos      token.Position // position of the call site
 }
 
 func (e *Error) Type() ObjectType {
@@ -218,7 +525,40 @@ func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
-type BuiltinFunction func(args ...Object) Object
+// Trace returns the error message followed by where it was raised and the
+// chain of calls it propagated through.
+func (e *Error) Trace() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+
+	if e.Pos.IsValid() {
+		out.WriteString("\n    at " + e.Pos.String())
+	}
+
+	for idx, frame := range e.Stack {
+		if idx == maxTraceFrames {
+			out.WriteString(fmt.Sprintf("\n    ... %d more", len(e.Stack)-idx))
+			break
+		}
+
+		name := frame.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+
+		out.WriteString(fmt.Sprintf("\n    in %s called at %s", name, frame.Pos))
+	}
+
+	return out.String()
+}
+
+// Caller calls a function value, a Function, Closure or Builtin, with the
+// given arguments. It is provided by the engine running a builtin so the
+// builtin can call back into the program, errors are returned as *Error.
+type Caller func(fn Object, args []Object) Object
+
+type BuiltinFunction func(call Caller, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
@@ -231,3 +571,50 @@ func (b *Builtin) Type() ObjectType {
 func (b *Builtin) Inspect() string {
 	return "builtin function"
 }
+
+// CompiledFunction is a function lowered to bytecode by the compiler.
+type CompiledFunction struct {
+	Name          string
+	Instructions  code.Instructions
+	Positions     code.PosTable
+	NumLocals     int
+	NumParameters int
+
+	// names of the locals and free variables by index, to report the ones
+	// read before they are set
+	LocalNames []string
+	FreeNames  []string
+
+	// source of the function, nil for the main program
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+}
+
+func (cf *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJ
+}
+
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure is a CompiledFunction along with the free variables it captured,
+// it is what the vm calls and reports itself as a plain FUNCTION so both
+// backends look the same to Monkey programs.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+	Name string // like Function.Name, starts out as the name of Fn
+}
+
+func (c *Closure) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+
+func (c *Closure) Inspect() string {
+	if c.Fn.Body == nil {
+		return fmt.Sprintf("Closure[%p]", c)
+	}
+
+	return functionSource(c.Fn.Parameters, c.Fn.Body)
+}
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/aryuuu/gonkey-lang/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +25,143 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	testCases := []struct {
+		float    *Float
+		other    Hashable
+		expected bool
+	}{
+		{&Float{Value: 1.5}, &Float{Value: 1.5}, true},
+		{&Float{Value: 1.5}, &Float{Value: 2.5}, false},
+		{&Float{Value: 2}, &Integer{Value: 2}, true},
+		{&Float{Value: -3}, &Integer{Value: -3}, true},
+		{&Float{Value: 2.5}, &Integer{Value: 2}, false},
+		{&Float{Value: 1e20}, &BigInt{Value: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}, true},
+	}
+
+	for _, tc := range testCases {
+		same := tc.float.HashKey() == tc.other.HashKey()
+		if same != tc.expected {
+			t.Errorf("%s and %s: expected same hash key to be %t", tc.float.Inspect(), tc.other.(Object).Inspect(), tc.expected)
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	testCases := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{3, "3.0"},
+		{-0.5, "-0.5"},
+		{1e-9, "1e-09"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tc := range testCases {
+		actual := (&Float{Value: tc.value}).Inspect()
+		if actual != tc.expected {
+			t.Errorf("wrong inspect for %g. expected=%q, got=%q", tc.value, tc.expected, actual)
+		}
+	}
+}
+
+func TestErrorTrace(t *testing.T) {
+	err := &Error{
+		Message: "type mismatch: INTEGER + BOOLEAN",
+		Pos:     token.Position{Line: 2, Column: 5},
+		Stack: []Frame{
+			{Function: "inner", Pos: token.Position{Line: 4, Column: 3}},
+			{Pos: token.Position{Line: 6, Column: 1}},
+		},
+	}
+
+	expected := `ERROR: type mismatch: INTEGER + BOOLEAN
+    at 2:5
+    in inner called at 4:3
+    in <anonymous> called at 6:1`
+
+	if err.Trace() != expected {
+		t.Errorf("wrong trace. expected=%q, got=%q", expected, err.Trace())
+	}
+}
+
+func TestMapOrder(t *testing.T) {
+	m := NewMap()
+	for _, key := range []string{"c", "a", "b"} {
+		str := &String{Value: key}
+		m.Set(str.HashKey(), HashPair{Key: str, Value: &Integer{Value: 1}})
+	}
+
+	a := &String{Value: "a"}
+	m.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+
+	if m.Inspect() != "{c: 1, a: 2, b: 1}" {
+		t.Errorf("wrong order after Set. got=%q", m.Inspect())
+	}
+
+	if !m.Delete(a.HashKey()) || m.Delete(a.HashKey()) {
+		t.Errorf("Delete should report only the first removal")
+	}
+
+	if m.Inspect() != "{c: 1, b: 1}" {
+		t.Errorf("wrong order after Delete. got=%q", m.Inspect())
+	}
+
+	m.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+
+	if m.Inspect() != "{c: 1, b: 1, a: 3}" {
+		t.Errorf("a deleted key should be set at the end. got=%q", m.Inspect())
+	}
+}
+
+func TestTupleAndSetHashKeys(t *testing.T) {
+	tuple := func(elements ...Object) *Tuple {
+		return &Tuple{Elements: elements}
+	}
+	set := func(elements ...Object) *Set {
+		s := NewSet()
+		for _, el := range elements {
+			key, _ := HashKeyOf(el)
+			s.Add(key, el)
+		}
+		return s
+	}
+	one, two := &Integer{Value: 1}, &String{Value: "two"}
+
+	testCases := []struct {
+		left     Object
+		right    Object
+		expected bool
+	}{
+		{tuple(one, two), tuple(&Integer{Value: 1}, &String{Value: "two"}), true},
+		{tuple(one, two), tuple(two, one), false},
+		{tuple(one), tuple(one, one), false},
+		{tuple(tuple(one)), tuple(tuple(one)), true},
+		{tuple(), tuple(), true},
+		{set(one, two), set(two, one), true},
+		{set(one, two), set(one), false},
+		{set(one), tuple(one), false},
+	}
+
+	for i, tc := range testCases {
+		left, ok := HashKeyOf(tc.left)
+		if !ok {
+			t.Fatalf("[%d] %s is not hashable", i, tc.left.Inspect())
+		}
+		right, ok := HashKeyOf(tc.right)
+		if !ok {
+			t.Fatalf("[%d] %s is not hashable", i, tc.right.Inspect())
+		}
+
+		if (left == right) != tc.expected {
+			t.Errorf("[%d] %s and %s: expected equal hash keys to be %t", i, tc.left.Inspect(), tc.right.Inspect(), tc.expected)
+		}
+	}
+
+	if _, ok := HashKeyOf(tuple(one, tuple(&Array{}))); ok {
+		t.Errorf("a tuple holding an array should not be hashable")
+	}
+}
@@ -1,10 +1,14 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/aryuuu/gonkey-lang/ast"
+	"github.com/aryuuu/gonkey-lang/diagnostic"
 	"github.com/aryuuu/gonkey-lang/lexer"
 	"github.com/aryuuu/gonkey-lang/token"
 )
@@ -12,34 +16,89 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x = y
+	NULLISH     // ??
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
-	LESSGREATER // > or <
+	LESSGREATER // > < >= <=
+	UNION       // |
+	INTERSECT   // &
 	SUM         // +
-	PRODUCT     // *
+	PRODUCT     // * / %
 	PREFIX      // -X or !X
 	CALL        // foo(bar)
 	INDEX       // array[index]
 )
 
+// diagnostic codes reported by the parser
+const (
+	CodeUnexpectedToken = "P001"
+	CodeNoPrefixParseFn = "P002"
+	CodeInvalidInteger  = "P003"
+	CodeInvalidAssign   = "P004"
+	CodeOutsideLoop     = "P005"
+	CodeInvalidFloat    = "P006"
+	CodeInvalidString   = "P007"
+	CodeInvalidComment  = "P008"
+	CodeJumpInValue     = "P009"
+)
+
 var precedence = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:           ASSIGN,
+	token.NULLISH:          NULLISH,
+	token.OR:               OR,
+	token.AND:              AND,
+	token.EQ:               EQUALS,
+	token.NOT_EQ:           EQUALS,
+	token.LT:               LESSGREATER,
+	token.GT:               LESSGREATER,
+	token.LT_EQ:            LESSGREATER,
+	token.GT_EQ:            LESSGREATER,
+	token.PIPE:             UNION,
+	token.AMPERSAND:        INTERSECT,
+	token.PLUS:             SUM,
+	token.MINUS:            SUM,
+	token.SLASH:            PRODUCT,
+	token.ASTERISK:         PRODUCT,
+	token.PERCENT:          PRODUCT,
+	token.LPAREN:           CALL,
+	token.LBRACKET:         INDEX,
+	token.OPTIONAL_BRACKET: INDEX,
+	token.OPTIONAL_DOT:     INDEX,
 }
 
 type Parser struct {
 	l *lexer.Lexer
 
-	errors    []string
+	errors    []diagnostic.Diagnostic
 	curToken  token.Token
 	peekToken token.Token
+	// curDoc and peekDoc are the /// comments right before the tokens.
+	curDoc  string
+	peekDoc string
+
+	// panicking is set once an error is reported and cleared when the parser
+	// has skipped ahead to a statement boundary, errors reported in between
+	// are most likely caused by the first one so they are dropped.
+	panicking bool
+	// depth is the number of braces opened up to and including curToken.
+	depth int
+	// eof is the position of the EOF token once the lexer produced it.
+	eof token.Position
+	// loopDepth is the number of loops around the current statement within
+	// the current function, break and continue are only valid inside one.
+	loopDepth int
+	// break and continue are only valid as statements of a loop body or of
+	// an if expression that is itself such a statement, anywhere else they
+	// would leave the values computed around them behind. statementIf is
+	// set while the if starting an expression statement is parsed, inValue
+	// while the blocks of an if used as a value are.
+	statementIf bool
+	inValue     bool
+	// loopJumps are the break and continue tokens parsed for the innermost
+	// loop so far.
+	loopJumps []token.Token
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -52,19 +111,23 @@ type (
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{l: l}
-	p.errors = []string{}
+	p.errors = []diagnostic.Diagnostic{}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefixParseFn(token.IDENT, p.parseIdentifier)
 	p.registerPrefixParseFn(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixParseFn(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixParseFn(token.TRUE, p.parseBoolean)
 	p.registerPrefixParseFn(token.FALSE, p.parseBoolean)
+	p.registerPrefixParseFn(token.NULL, p.parseNull)
 	p.registerPrefixParseFn(token.BANG, p.parsePrefixExpression)
 	p.registerPrefixParseFn(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixParseFn(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefixParseFn(token.IF, p.parseIfExpression)
 	p.registerPrefixParseFn(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefixParseFn(token.STRING, p.parseStringLiteral)
+	p.registerPrefixParseFn(token.STRING_HEAD, p.parseInterpolatedString)
+	p.registerPrefixParseFn(token.ERROR, p.parseErrorToken)
 	p.registerPrefixParseFn(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefixParseFn(token.LBRACE, p.parseMapLiteral)
 
@@ -73,12 +136,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixParseFn(token.MINUS, p.parseInfixExpression)
 	p.registerInfixParseFn(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfixParseFn(token.SLASH, p.parseInfixExpression)
+	p.registerInfixParseFn(token.PERCENT, p.parseInfixExpression)
+	p.registerInfixParseFn(token.AND, p.parseInfixExpression)
+	p.registerInfixParseFn(token.OR, p.parseInfixExpression)
+	p.registerInfixParseFn(token.NULLISH, p.parseInfixExpression)
 	p.registerInfixParseFn(token.LT, p.parseInfixExpression)
 	p.registerInfixParseFn(token.GT, p.parseInfixExpression)
+	p.registerInfixParseFn(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfixParseFn(token.GT_EQ, p.parseInfixExpression)
 	p.registerInfixParseFn(token.EQ, p.parseInfixExpression)
 	p.registerInfixParseFn(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfixParseFn(token.PIPE, p.parseInfixExpression)
+	p.registerInfixParseFn(token.AMPERSAND, p.parseInfixExpression)
 	p.registerInfixParseFn(token.LPAREN, p.parseCallExpression)
 	p.registerInfixParseFn(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixParseFn(token.OPTIONAL_BRACKET, p.parseIndexExpression)
+	p.registerInfixParseFn(token.OPTIONAL_DOT, p.parseOptionalMember)
+	p.registerInfixParseFn(token.ASSIGN, p.parseAssignExpression)
 
 	// call next token twice to the curToken and peekToken are set
 	p.nextToken()
@@ -89,7 +163,20 @@ func New(l *lexer.Lexer) *Parser {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curDoc = p.peekDoc
+	p.peekToken, p.peekDoc = p.readToken()
+	if p.peekTokenIs(token.EOF) {
+		p.eof = p.peekToken.Span.Start
+	}
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.depth += 1
+	case token.RBRACE:
+		if p.depth > 0 {
+			p.depth -= 1
+		}
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -99,7 +186,9 @@ func (p *Parser) ParseProgram() *ast.Program {
 	for !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(0)
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
@@ -108,24 +197,104 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
-func (p *Parser) GetErrors() []string {
+// synchronize skips tokens until the end of the broken statement so parsing
+// can resume at the next one. depth is the brace depth of the enclosing
+// block, the parser stops on a `;` at that depth, right before a `let` or
+// `return`, or right before or on the `}` closing the block.
+func (p *Parser) synchronize(depth int) {
+	p.panicking = false
+
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.RBRACE) && p.depth < depth {
+			return
+		}
+
+		if p.depth == depth {
+			if p.curTokenIs(token.SEMICOLON) {
+				return
+			}
+
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.RBRACE:
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
+func (p *Parser) GetErrors() []diagnostic.Diagnostic {
 	return p.errors
 }
 
+// Incomplete reports whether every error was caused by the input ending too
+// early, in which case more input could still turn it into a valid program.
+func (p *Parser) Incomplete() bool {
+	if len(p.errors) == 0 {
+		return false
+	}
+
+	for _, err := range p.errors {
+		if err.Span.Start != p.eof {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Parser) addError(code string, span token.Span, msg string, hints ...string) {
+	if p.panicking {
+		return
+	}
+
+	p.panicking = true
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Code:     code,
+		Message:  msg,
+		Span:     span,
+		Hints:    hints,
+	})
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 		// return nil
 	}
 }
 
+// readToken returns the next token from the lexer along with the lines of
+// the doc comments in front of it.
+func (p *Parser) readToken() (token.Token, string) {
+	var doc []string
+
+	tok := p.l.NextToken()
+	for tok.Type == token.DOC_COMMENT {
+		doc = append(doc, tok.Literal)
+		tok = p.l.NextToken()
+	}
+
+	return tok, strings.Join(doc, "\n")
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Doc: p.curDoc}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -165,11 +334,148 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth += 1
+	inValue, loopJumps := p.inValue, p.loopJumps
+	p.inValue = false
+	defer func() {
+		p.loopDepth -= 1
+		p.inValue, p.loopJumps = inValue, loopJumps
+	}()
+
+	return p.parseBlockStatement()
+}
+
+// checkLoopJump reports a break or continue outside of a loop body, or one
+// inside an expression.
+func (p *Parser) checkLoopJump() bool {
+	switch {
+	case p.loopDepth == 0:
+		p.addError(CodeOutsideLoop, p.curToken.Span, fmt.Sprintf("%s outside of a loop", p.curToken.Literal))
+		return false
+	case p.inValue:
+		p.addError(CodeJumpInValue, p.curToken.Span, fmt.Sprintf("%s inside an expression", p.curToken.Literal),
+			"break and continue can only be statements of a loop body, or of an if that is one")
+		return false
+	}
+
+	p.loopJumps = append(p.loopJumps, p.curToken)
+	return true
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if !p.checkLoopJump() {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if !p.checkLoopJump() {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
+	jumps := len(p.loopJumps)
+	p.statementIf = p.curTokenIs(token.IF)
 	stmt.Expression = p.parseExpression(LOWEST)
 
+	// the if turned out to be the operand of an operator
+	if _, ok := stmt.Expression.(*ast.IfExpression); !ok && len(p.loopJumps) > jumps {
+		jump := p.loopJumps[jumps]
+		p.addError(CodeJumpInValue, jump.Span, fmt.Sprintf("%s inside an expression", jump.Literal),
+			"break and continue can only be statements of a loop body, or of an if that is one")
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -179,7 +485,12 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 func (p *Parser) noPrefixParseError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	hint := fmt.Sprintf("%q cannot start an expression", p.curToken.Literal)
+	if t == token.EOF {
+		hint = "the input ended in the middle of an expression"
+	}
+
+	p.addError(CodeNoPrefixParseFn, p.curToken.Span, msg, hint)
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
@@ -214,9 +525,34 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if bigValue, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = bigValue
+			return lit
+		}
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(CodeInvalidInteger, p.curToken.Span, msg)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{
+		Token: p.curToken,
+		Value: 0,
+	}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.addError(CodeInvalidFloat, p.curToken.Span, msg, "the value is out of range for a 64-bit float")
 		return nil
 	}
 
@@ -238,23 +574,70 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+// parseGroupedExpression parses (x), or a tuple when the parentheses are
+// empty or there is a comma after the first expression.
 func (p *Parser) parseGroupedExpression() ast.Expression {
-	p.nextToken()
+	tok := p.curToken
 
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return &ast.TupleLiteral{Token: tok, Elements: []ast.Expression{}, EndToken: p.curToken}
+	}
+
+	p.nextToken()
 	exp := p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COMMA) {
+		return p.parseTupleLiteral(tok, exp)
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
+	switch exp.(type) {
+	case *ast.CallExpression, *ast.IndexExpression, *ast.SliceExpression:
+		return &ast.GroupedExpression{Token: tok, Expression: exp, EndToken: p.curToken}
+	}
+
 	return exp
 }
 
+// parseTupleLiteral parses the rest of a tuple from the comma after its
+// first element on, a trailing comma is allowed.
+func (p *Parser) parseTupleLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	tuple := &ast.TupleLiteral{Token: tok, Elements: []ast.Expression{first}}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RPAREN) {
+			break
+		}
+
+		p.nextToken()
+		tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	tuple.EndToken = p.curToken
+
+	return tuple
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{
 		Token: p.curToken,
 	}
 
+	if !p.statementIf {
+		inValue := p.inValue
+		p.inValue = true
+		defer func() { p.inValue = inValue }()
+	}
+	p.statementIf = false
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
@@ -300,7 +683,12 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
+	// a function body starts outside of any loop, even when the function
+	// itself is defined inside one
+	loopDepth, inValue, loopJumps := p.loopDepth, p.inValue, p.loopJumps
+	p.loopDepth, p.inValue, p.loopJumps = 0, false, nil
 	fl.Body = p.parseBlockStatement()
+	p.loopDepth, p.inValue, p.loopJumps = loopDepth, inValue, loopJumps
 
 	return fl
 }
@@ -312,11 +700,69 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = p.appendStringPart(str.Parts)
+
+	for !p.curTokenIs(token.STRING_TAIL) {
+		p.nextToken()
+		if p.curTokenIs(token.STRING_MID) || p.curTokenIs(token.STRING_TAIL) {
+			p.addError(CodeInvalidString, p.curToken.Span, "empty interpolation in string")
+			return nil
+		}
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+		if p.peekTokenIs(token.ERROR) {
+			p.nextToken()
+			return p.parseErrorToken()
+		}
+
+		if !p.peekTokenIs(token.STRING_MID) && !p.peekTokenIs(token.STRING_TAIL) {
+			msg := fmt.Sprintf("expected } to close the interpolation, got %s instead", p.peekToken.Type)
+			p.addError(CodeUnexpectedToken, p.peekToken.Span, msg)
+			return nil
+		}
+
+		p.nextToken()
+		str.Parts = p.appendStringPart(str.Parts)
+	}
+	str.EndToken = p.curToken
+
+	return str
+}
+
+// appendStringPart adds the text of the current string token to parts,
+// unless there is none.
+func (p *Parser) appendStringPart(parts []ast.Expression) []ast.Expression {
+	if p.curToken.Literal == "" {
+		return parts
+	}
+
+	return append(parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+}
+
+// parseErrorToken reports a literal or a comment the lexer could not read.
+func (p *Parser) parseErrorToken() ast.Expression {
+	switch p.curToken.Literal {
+	case lexer.UnterminatedComment:
+		p.addError(CodeInvalidComment, p.curToken.Span, p.curToken.Literal, "the comment is never closed, add the missing */")
+		return nil
+	case lexer.UnterminatedString:
+		p.addError(CodeInvalidString, p.curToken.Span, p.curToken.Literal, "the string is never closed, add the missing quote")
+		return nil
+	}
+
+	p.addError(CodeInvalidString, p.curToken.Span, p.curToken.Literal)
+
+	return nil
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{
-		Token:    p.curToken,
-		Elements: p.parseExpressionList(token.RBRACKET),
+		Token: p.curToken,
 	}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.EndToken = p.curToken
 
 	return array
 }
@@ -345,19 +791,72 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseIndexExpression parses left[index], or the slice left[low:high] when
+// there is a colon in the brackets. Both are optional after ?[ rather than [.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{
-		Token: p.curToken,
-		Left:  left,
+	tok := p.curToken
+	optional := p.curTokenIs(token.OPTIONAL_BRACKET)
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
 	}
 
-	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(tok, left, index, optional)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
+	return &ast.IndexExpression{
+		Token:    tok,
+		Left:     left,
+		Index:    index,
+		Optional: optional,
+		EndToken: p.curToken,
+	}
+}
+
+// parseOptionalMember parses left?.name, which is short for left?["name"].
+func (p *Parser) parseOptionalMember(left ast.Expression) ast.Expression {
+	tok := p.curToken
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	return &ast.IndexExpression{
+		Token:    tok,
+		Left:     left,
+		Index:    &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal},
+		Optional: true,
+		EndToken: p.curToken,
+	}
+}
+
+// parseSliceExpression parses the rest of a slice from the colon on.
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression, optional bool) ast.Expression {
+	exp := &ast.SliceExpression{
+		Token:    tok,
+		Left:     left,
+		Low:      low,
+		Optional: optional,
+	}
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.EndToken = p.curToken
+
 	return exp
 }
 
@@ -371,6 +870,11 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		p.nextToken()
 		key := p.parseExpression(LOWEST)
 
+		// without a colon after the first element this is a set
+		if len(exp.Keys) == 0 && !p.peekTokenIs(token.COLON) {
+			return p.parseSetLiteral(exp.Token, key)
+		}
+
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -378,6 +882,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		exp.Pairs[key] = value
+		exp.Keys = append(exp.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -387,16 +892,41 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	exp.EndToken = p.curToken
 
 	return exp
 }
 
+// parseSetLiteral parses the rest of a set after its first element, a
+// trailing comma is allowed like in a map literal.
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	set := &ast.SetLiteral{Token: tok, Elements: []ast.Expression{first}}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RBRACE) {
+			break
+		}
+
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	set.EndToken = p.curToken
+
+	return set
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	ce := &ast.CallExpression{
 		Token:    p.curToken,
 		Function: function,
 	}
 	ce.Arguments = p.parseExpressionList(token.RPAREN)
+	ce.EndToken = p.curToken
 
 	return ce
 }
@@ -466,17 +996,30 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		Statements: []ast.Statement{},
 	}
 
+	depth := p.depth
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.panicking {
+			p.synchronize(depth)
+			if p.curTokenIs(token.RBRACE) && p.depth < depth {
+				break
+			}
+		} else if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.EOF) && !p.panicking {
+		msg := fmt.Sprintf("expected next token to be %s, got %s instead", token.RBRACE, token.EOF)
+		p.addError(CodeUnexpectedToken, p.curToken.Span, msg)
+		p.errors[len(p.errors)-1].Notes = []string{fmt.Sprintf("block opened at %s", block.Token.Span.Start)}
+	}
+	block.EndToken = p.curToken
+
 	return block
 }
 
@@ -494,6 +1037,44 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	if group, ok := target.(*ast.GroupedExpression); ok {
+		target = group.Expression
+	}
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+	case *ast.IndexExpression:
+		if target.Optional {
+			p.addError(CodeInvalidAssign, ast.SpanOf(target), fmt.Sprintf("cannot assign to %s", target.String()),
+				"optional chaining cannot be assigned to")
+			return nil
+		}
+	case nil:
+		// the target already failed to parse and reported why
+		return nil
+	default:
+		p.addError(CodeInvalidAssign, ast.SpanOf(target), fmt.Sprintf("cannot assign to %s", target.String()),
+			"only names and index expressions can be assigned to")
+		return nil
+	}
+
+	expression := &ast.AssignExpression{
+		Token:  p.curToken,
+		Target: target,
+	}
+
+	// assignment is right associative, a = b = c assigns c to b and then to a
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.curToken,
@@ -512,7 +1093,7 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
 
-	p.errors = append(p.errors, msg)
+	p.addError(CodeUnexpectedToken, p.peekToken.Span, msg)
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
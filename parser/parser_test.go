@@ -32,7 +32,7 @@ func TestLetStatements(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		l := lexer.New(tc.input)
+		l := lexer.New("", tc.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -53,6 +53,41 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDocComments(t *testing.T) {
+	input := `
+	/// Adds two numbers.
+	///
+	/// Both must be integers.
+	let add = fn(a, b) { a + b };
+	let y = 1; // not a doc comment
+	/// Dangling.
+	add(1, 2);
+	/* not a doc comment either */
+	let z = 2;
+	`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 4)
+	}
+
+	expected := []string{"Adds two numbers.\n\nBoth must be integers.", "", "", ""}
+	for i, doc := range expected {
+		let, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+
+		if let.Doc != doc {
+			t.Errorf("[%d] wrong doc. expected=%q, got=%q", i, doc, let.Doc)
+		}
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	testCases := []struct {
 		input         string
@@ -73,7 +108,7 @@ func TestReturnStatement(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		l := lexer.New(tc.input)
+		l := lexer.New("", tc.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -105,7 +140,7 @@ func TestReturnStatement(t *testing.T) {
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 
 	program := p.ParseProgram()
@@ -130,7 +165,7 @@ func TestIdentifierExpression(t *testing.T) {
 func TestBooleanExpression(t *testing.T) {
 	input := "true;"
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 
 	program := p.ParseProgram()
@@ -155,7 +190,7 @@ func TestBooleanExpression(t *testing.T) {
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := `5;`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -172,10 +207,69 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	testLiteralExpression(t, stmt.Expression, 5)
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := `99999999999999999999;`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IntegerLiteral, got=%T", stmt.Expression)
+	}
+
+	if literal.Big == nil || literal.Big.String() != "99999999999999999999" {
+		t.Errorf("literal.Big is not 99999999999999999999, got=%v", literal.Big)
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E3;", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New("", tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 1)
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FloatLiteral, got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value is not %g, got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -199,10 +293,47 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStringExpression(t *testing.T) {
+	input := `"a ${x} b ${1 + 2}${"c"}"`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 1)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+	}
+
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.InterpolatedString, got=%T", stmt.Expression)
+	}
+
+	if len(str.Parts) != 5 {
+		t.Fatalf("len(str.Parts) is not 5, got=%d", len(str.Parts))
+	}
+
+	testStringLiteral(t, str.Parts[0], "a ")
+	testIdentifier(t, str.Parts[1], "x")
+	testStringLiteral(t, str.Parts[2], " b ")
+	testInfixExpression(t, str.Parts[3], 1, "+", 2)
+	testStringLiteral(t, str.Parts[4], "c")
+
+	if str.String() != "a ${x} b ${(1 + 2)}c" {
+		t.Errorf("str.String() wrong, got=%q", str.String())
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := `[1, 2 * 2, 3 + 3]`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -233,7 +364,7 @@ func TestParsingArrayLiterals(t *testing.T) {
 func TestParsingMapLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -271,12 +402,16 @@ func TestParsingMapLiteralsStringKeys(t *testing.T) {
 		expectedValue := expected[literal.String()]
 		testIntegerLiteral(t, value, expectedValue)
 	}
+
+	if mapLit.String() != "{one:1, two:2, three:3}" {
+		t.Errorf("keys are not in source order, got=%q", mapLit.String())
+	}
 }
 
 func TestParsingEmptyMapLiteral(t *testing.T) {
 	input := `{}`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -303,7 +438,7 @@ func TestParsingEmptyMapLiteral(t *testing.T) {
 func TestParsingMapLiteralsWithExpressions(t *testing.T) {
 	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -358,7 +493,7 @@ func TestParsingMapLiteralsWithExpressions(t *testing.T) {
 func TestParsingIndexExpression(t *testing.T) {
 	input := `myArray[1 + 1]`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -381,6 +516,166 @@ func TestParsingIndexExpression(t *testing.T) {
 	testInfixExpression(t, indexExp.Index, 1, "+", 1)
 }
 
+func TestParsingTupleAndSetLiterals(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		length   int
+	}{
+		{"()", "()", 0},
+		{"(1,)", "(1,)", 1},
+		{"(1, 2 * 3)", "(1, (2 * 3))", 2},
+		{"(1, (2, 3),)", "(1, (2, 3))", 2},
+		{"{1}", "{1}", 1},
+		{"{1, 2 + 3}", "{1, (2 + 3)}", 2},
+		{`{"a", (1, 2),}`, "{a, (1, 2)}", 2},
+	}
+
+	for _, tc := range testCases {
+		l := lexer.New("", tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+		}
+
+		var elements []ast.Expression
+		switch literal := stmt.Expression.(type) {
+		case *ast.TupleLiteral:
+			elements = literal.Elements
+		case *ast.SetLiteral:
+			elements = literal.Elements
+		default:
+			t.Fatalf("%s: stmt.Expression is not a tuple or set literal, got=%T", tc.input, stmt.Expression)
+		}
+
+		if len(elements) != tc.length {
+			t.Errorf("%s: wrong number of elements, expected=%d, got=%d", tc.input, tc.length, len(elements))
+		}
+
+		if stmt.Expression.String() != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestParsingSliceExpression(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		hasLow   bool
+		hasHigh  bool
+	}{
+		{"a[1:3]", "(a[1:3])", true, true},
+		{"a[:2]", "(a[:2])", false, true},
+		{"a[i + 1:]", "(a[(i + 1):])", true, false},
+		{"a[:]", "(a[:])", false, false},
+		{"a[-2:-1]", "(a[(-2):(-1)])", true, true},
+	}
+
+	for _, tc := range testCases {
+		l := lexer.New("", tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+		}
+
+		sliceExp, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.SliceExpression, got=%T", stmt.Expression)
+		}
+
+		testIdentifier(t, sliceExp.Left, "a")
+
+		if (sliceExp.Low != nil) != tc.hasLow || (sliceExp.High != nil) != tc.hasHigh {
+			t.Errorf("%s: wrong bounds, low=%v, high=%v", tc.input, sliceExp.Low, sliceExp.High)
+		}
+
+		if sliceExp.String() != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, sliceExp.String())
+		}
+	}
+}
+
+func TestParsingGroupedChains(t *testing.T) {
+	testCases := []struct {
+		input   string
+		grouped bool
+	}{
+		{"(a?[0])", true},
+		{"(f(1))", true},
+		{"(a[1:])", true},
+		{"(a + b)", false},
+		{"(a)", false},
+	}
+
+	for _, tc := range testCases {
+		l := lexer.New("", tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+		}
+
+		_, grouped := stmt.Expression.(*ast.GroupedExpression)
+		if grouped != tc.grouped {
+			t.Errorf("%s: expected grouped=%t, got=%T", tc.input, tc.grouped, stmt.Expression)
+		}
+	}
+}
+
+func TestParsingAssignExpression(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTarget string
+		expectedValue  string
+	}{
+		{"x = 5;", "x", "5"},
+		{"x = y + 1;", "x", "(y + 1)"},
+		{`m["key"] = [1];`, `(m[key])`, "[1]"},
+		{"a[0][1] = 2;", "((a[0])[1])", "2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New("", tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 1)
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T", program.Statements[0])
+		}
+
+		assign, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression, got=%T", stmt.Expression)
+		}
+
+		if assign.Target.String() != tt.expectedTarget {
+			t.Errorf("wrong target. expected=%q, got=%q", tt.expectedTarget, assign.Target.String())
+		}
+
+		if assign.Value.String() != tt.expectedValue {
+			t.Errorf("wrong value. expected=%q, got=%q", tt.expectedValue, assign.Value.String())
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -394,7 +689,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}
 
 	for idx, tt := range prefixTests {
-		l := lexer.New(tt.input)
+		l := lexer.New("", tt.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -437,11 +732,15 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 != 5;", 5, "!=", 5},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
 		{"false == false", false, "==", false},
 	}
 
 	for idx, tt := range infixTests {
-		l := lexer.New(tt.input)
+		l := lexer.New("", tt.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -493,6 +792,34 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a + b / c",
 			"(a + (b / c))",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && b == c",
+			"((a < b) && (b == c))",
+		},
+		{
+			"a + b >= c * d == true",
+			"(((a + b) >= (c * d)) == true)",
+		},
+		{
+			"a <= b != b > c",
+			"((a <= b) != (b > c))",
+		},
+		{
+			"x = a || b",
+			"x = (a || b)",
+		},
 		{
 			"a + b * c + d / e - f",
 			"(((a + (b * c)) + (d / e)) - f)",
@@ -569,10 +896,46 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a ?? b || c ?? d",
+			"((a ?? (b || c)) ?? d)",
+		},
+		{
+			"a?.b?[0] + null",
+			"(((a?[b])?[0]) + null)",
+		},
+		{
+			"-a?[1:]",
+			"(-(a?[1:]))",
+		},
+		{
+			"a | b & c - d",
+			"(a | (b & (c - d)))",
+		},
+		{
+			"a & b | c == d",
+			"(((a & b) | c) == d)",
+		},
+		{
+			"(1) + (2, 3)",
+			"(1 + (2, 3))",
+		},
+		{
+			"a[1:n - 1][0] + -b[:2][1]",
+			"(((a[1:(n - 1)])[0]) + (-((b[:2])[1])))",
+		},
+		{
+			"a = b = c + 1",
+			"a = b = (c + 1)",
+		},
+		{
+			"a[i + 1] = x * 2",
+			"(a[(i + 1)]) = (x * 2)",
+		},
 	}
 
 	for idx, tt := range tests {
-		l := lexer.New(tt.input)
+		l := lexer.New("", tt.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -587,7 +950,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 func TestIfExpression(t *testing.T) {
 	input := `if (x < y ) { x }`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 
 	program := p.ParseProgram()
@@ -632,7 +995,7 @@ func TestIfExpression(t *testing.T) {
 func TestIfElseExpression(t *testing.T) {
 	input := `if (x < y ) { x } else { y }`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 
 	program := p.ParseProgram()
@@ -687,10 +1050,74 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x = x + 1; break; continue; }`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 1)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement, got=%T", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body has the wrong number of statements, got=%d instead of %d", len(stmt.Body.Statements), 3)
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf("body.Statements[1] is not ast.BreakStatement, got=%T", stmt.Body.Statements[1])
+	}
+
+	if _, ok := stmt.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[2] is not ast.ContinueStatement, got=%T", stmt.Body.Statements[2])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (item in items) { item }
+	[1]`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has the wrong number of statements, got=%d instead of %d", len(program.Statements), 2)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement, got=%T", program.Statements[0])
+	}
+
+	testIdentifier(t, stmt.Variable, "item")
+	testIdentifier(t, stmt.Iterable, "items")
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body has the wrong number of statements, got=%d instead of %d", len(stmt.Body.Statements), 1)
+	}
+
+	if program.String() != "for(item in items) item[1]" {
+		t.Errorf("wrong program. got=%q", program.String())
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 
 	program := p.ParseProgram()
@@ -749,7 +1176,7 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		l := lexer.New(tt.input)
+		l := lexer.New("", tt.input)
 		p := New(l)
 		program := p.ParseProgram()
 		checkParseErrors(t, p)
@@ -770,7 +1197,7 @@ func TestFunctionParameterParsing(t *testing.T) {
 func TestCallExpressionParsing(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5);`
 
-	l := lexer.New(input)
+	l := lexer.New("", input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParseErrors(t, p)
@@ -834,8 +1261,8 @@ func checkParseErrors(t *testing.T, p *Parser) {
 	}
 
 	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	for _, err := range errors {
+		t.Errorf("parser error: %q", err.Error())
 	}
 	t.FailNow()
 }
@@ -920,6 +1347,21 @@ func testBoolean(t *testing.T, exp ast.Expression, value bool) bool {
 	return true
 }
 
+func testStringLiteral(t *testing.T, exp ast.Expression, value string) bool {
+	str, ok := exp.(*ast.StringLiteral)
+	if !ok {
+		t.Errorf("exp is not *ast.StringLiteral, got=%T", exp)
+		return false
+	}
+
+	if str.Value != value {
+		t.Errorf("str.Value is not %q, got=%q", value, str.Value)
+		return false
+	}
+
+	return true
+}
+
 func testLiteralExpression(t *testing.T, exp ast.Expression, expected any) bool {
 	switch v := expected.(type) {
 	case int:
@@ -977,3 +1419,316 @@ func testPrefixExpression(t *testing.T, exp ast.Expression, operator string, rig
 
 	return true
 }
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStart string
+		expectedEnd   string
+	}{
+		{"a + b * c", "1:1", "1:10"},
+		{"-foo", "1:1", "1:5"},
+		{"add(1, 2)", "1:1", "1:10"},
+		{"[1, 2][0]", "1:1", "1:10"},
+		{`{"a": 1}`, "1:1", "1:9"},
+		{"let x = 5;", "1:1", "1:10"},
+		{"return x;", "1:1", "1:9"},
+		{"if (x) { y } else { z }", "1:1", "1:24"},
+		{"fn(x) {\n  x\n}", "1:1", "3:2"},
+		{`"a ${b} c"`, "1:1", "1:11"},
+	}
+
+	for idx, tt := range tests {
+		l := lexer.New("", tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt := program.Statements[0]
+		if stmt.Pos().String() != tt.expectedStart {
+			t.Errorf("[%d] wrong start position. expected=%s, got=%s", idx, tt.expectedStart, stmt.Pos())
+		}
+
+		if stmt.End().String() != tt.expectedEnd {
+			t.Errorf("[%d] wrong end position. expected=%s, got=%s", idx, tt.expectedEnd, stmt.End())
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedCode    string
+		expectedMessage string
+		expectedStart   string
+	}{
+		{
+			"let = 5;",
+			CodeUnexpectedToken,
+			"expected next token to be  IDENT, got = instead",
+			"1:5",
+		},
+		{
+			"let x = );",
+			CodeNoPrefixParseFn,
+			"no prefix parse function for ) found",
+			"1:9",
+		},
+		{
+			"09",
+			CodeInvalidInteger,
+			`could not parse "09" as integer`,
+			"1:1",
+		},
+		{
+			"let x = 1; x + 1 = 2;",
+			CodeInvalidAssign,
+			"cannot assign to (x + 1)",
+			"1:12",
+		},
+		{
+			"let a = [1]; a[0:1] = 2;",
+			CodeInvalidAssign,
+			"cannot assign to (a[0:1])",
+			"1:14",
+		},
+		{
+			"let a = {}; a?.b = 1;",
+			CodeInvalidAssign,
+			"cannot assign to (a?[b])",
+			"1:13",
+		},
+		{
+			"a?.1",
+			CodeUnexpectedToken,
+			"expected next token to be  IDENT, got  INT instead",
+			"1:4",
+		},
+		{
+			"(1, 2",
+			CodeUnexpectedToken,
+			"expected next token to be ), got  EOF instead",
+			"1:6",
+		},
+		{
+			"{1, 2: 3}",
+			CodeUnexpectedToken,
+			"expected next token to be }, got : instead",
+			"1:6",
+		},
+		{
+			"a[1:2",
+			CodeUnexpectedToken,
+			"expected next token to be ], got  EOF instead",
+			"1:6",
+		},
+		{
+			"let x = 1e999;",
+			CodeInvalidFloat,
+			`could not parse "1e999" as float`,
+			"1:9",
+		},
+		{
+			"let f = fn() { break; };",
+			CodeOutsideLoop,
+			"break outside of a loop",
+			"1:16",
+		},
+		{
+			"while (true) { let f = fn() { continue; }; }",
+			CodeOutsideLoop,
+			"continue outside of a loop",
+			"1:31",
+		},
+		{
+			"let r = []; for (x in [1,2]) { r = push(r, x + if (x == 1) { continue } else { 0 }) }; r",
+			CodeJumpInValue,
+			"continue inside an expression",
+			"1:62",
+		},
+		{
+			"for (x in [1]) { let a = [0, if (true) { break }]; }",
+			CodeJumpInValue,
+			"break inside an expression",
+			"1:42",
+		},
+		{
+			"while (true) { if (true) { break } + 1; }",
+			CodeJumpInValue,
+			"break inside an expression",
+			"1:28",
+		},
+		{
+			"while (true) { let y = if (true) { 1 } else { if (true) { continue } }; }",
+			CodeJumpInValue,
+			"continue inside an expression",
+			"1:59",
+		},
+		{
+			`let s = "abc;`,
+			CodeInvalidString,
+			"unterminated string",
+			"1:9",
+		},
+		{
+			`let s = "a\qb";`,
+			CodeInvalidString,
+			`unknown escape sequence \q`,
+			"1:9",
+		},
+		{
+			`"a ${} b"`,
+			CodeInvalidString,
+			"empty interpolation in string",
+			"1:6",
+		},
+		{
+			`"a ${x y} b"`,
+			CodeUnexpectedToken,
+			"expected } to close the interpolation, got  IDENT instead",
+			"1:8",
+		},
+		{
+			`"a ${x} \q"`,
+			CodeInvalidString,
+			`unknown escape sequence \q`,
+			"1:7",
+		},
+		{
+			"let x = 1; /* never closed",
+			CodeInvalidComment,
+			"unterminated comment",
+			"1:12",
+		},
+		{
+			"for (1 in xs) {}",
+			CodeUnexpectedToken,
+			"expected next token to be  IDENT, got  INT instead",
+			"1:6",
+		},
+	}
+
+	for idx, tt := range tests {
+		l := lexer.New("", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.GetErrors()
+		if len(errors) == 0 {
+			t.Fatalf("[%d] expected parser errors, got none", idx)
+		}
+
+		d := errors[0]
+		if d.Code != tt.expectedCode {
+			t.Errorf("[%d] wrong code. expected=%q, got=%q", idx, tt.expectedCode, d.Code)
+		}
+
+		if d.Message != tt.expectedMessage {
+			t.Errorf("[%d] wrong message. expected=%q, got=%q", idx, tt.expectedMessage, d.Message)
+		}
+
+		if d.Span.Start.String() != tt.expectedStart {
+			t.Errorf("[%d] wrong start. expected=%s, got=%s", idx, tt.expectedStart, d.Span.Start)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+	let a = 1;
+	let = 2;
+	let b = (1 + 2;
+	let c = fn(x) {
+		let y = x +;
+		y * 2
+	};
+	add(1, 2;
+	let d = 4;
+	`
+
+	l := lexer.New("", input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		"3:6",
+		"4:16",
+		"6:14",
+		"9:10",
+	}
+
+	errors := p.GetErrors()
+	if len(errors) != len(expectedErrors) {
+		for _, err := range errors {
+			t.Logf("parser error: %q", err.Error())
+		}
+		t.Fatalf("parser should have %d errors, got=%d", len(expectedErrors), len(errors))
+	}
+
+	for idx, expected := range expectedErrors {
+		if errors[idx].Span.Start.String() != expected {
+			t.Errorf("[%d] wrong error position. expected=%s, got=%s", idx, expected, errors[idx].Span.Start)
+		}
+	}
+
+	expectedStatements := []string{
+		"let a = 1;",
+		"let c = fn(x)(y * 2);",
+		"let d = 4;",
+	}
+
+	if len(program.Statements) != len(expectedStatements) {
+		t.Fatalf("program.Statements should have %d statements, got=%d", len(expectedStatements), len(program.Statements))
+	}
+
+	for idx, expected := range expectedStatements {
+		if program.Statements[idx].String() != expected {
+			t.Errorf("[%d] wrong statement. expected=%q, got=%q", idx, expected, program.Statements[idx].String())
+		}
+	}
+}
+
+func TestUnterminatedBlock(t *testing.T) {
+	input := `if (x) { y`
+
+	l := lexer.New("", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.GetErrors()
+	if len(errors) != 1 {
+		t.Fatalf("parser should have 1 error, got=%d", len(errors))
+	}
+
+	if len(errors[0].Notes) != 1 || errors[0].Notes[0] != "block opened at 1:8" {
+		t.Errorf("wrong notes. got=%q", errors[0].Notes)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let add = fn(a, b) {", true},
+		{"add(1, 2", true},
+		{"[1, 2", true},
+		{"1 +", true},
+		{"let x =", true},
+		{"if (x) { y } else {", true},
+		{`"a ${x`, true},
+		{"let x = 1;", false},
+		{"1)", false},
+		{"let = 5; add(1,", false},
+	}
+
+	for idx, tt := range tests {
+		l := lexer.New("", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if p.Incomplete() != tt.expected {
+			t.Errorf("[%d] wrong Incomplete() for %q. expected=%t, got=%t", idx, tt.input, tt.expected, p.Incomplete())
+		}
+	}
+}
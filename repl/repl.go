@@ -4,21 +4,32 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/aryuuu/gonkey-lang/diagnostic"
 	"github.com/aryuuu/gonkey-lang/evaluator"
 	"github.com/aryuuu/gonkey-lang/lexer"
 	"github.com/aryuuu/gonkey-lang/object"
 	"github.com/aryuuu/gonkey-lang/parser"
+	"github.com/aryuuu/gonkey-lang/token"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "
+)
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 
 	env := object.NewEnvironment()
+	pending := ""
 	for {
-		fmt.Fprintf(out, "%s", PROMPT)
+		if pending == "" {
+			fmt.Fprintf(out, "%s", PROMPT)
+		} else {
+			fmt.Fprintf(out, "%s", CONTINUATION_PROMPT)
+		}
 		scanned := scanner.Scan()
 
 		if !scanned {
@@ -26,16 +37,38 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
-		l := lexer.New(line)
+
+		// an empty line ends the continuation, reporting whatever is wrong
+		// with the input so far
+		force := pending != "" && strings.TrimSpace(line) == ""
+
+		input := line
+		if pending != "" {
+			input = pending + "\n" + line
+		}
+
+		l := lexer.New("", input)
 		p := parser.New(l)
 
 		program := p.ParseProgram()
+		if !force && (p.Incomplete() || hasUnterminatedToken(input)) {
+			pending = input
+			continue
+		}
+		pending = ""
+
 		if len(p.GetErrors()) != 0 {
-			printParserError(out, p.GetErrors())
+			printParserError(out, input, p.GetErrors())
 			continue
 		}
 
 		evaluated := evaluator.Eval(program, env)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, errObj.Trace())
+			io.WriteString(out, "\n")
+			continue
+		}
+
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -43,8 +76,24 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserError(out io.Writer, errors []string) {
-	for _, err := range errors {
-		io.WriteString(out, "\t"+err+"\n")
+// hasUnterminatedToken reports whether input ends inside a string literal
+// or a block comment.
+func hasUnterminatedToken(input string) bool {
+	l := lexer.New("", input)
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type != token.ERROR {
+			continue
+		}
+
+		if tok.Literal == lexer.UnterminatedString || tok.Literal == lexer.UnterminatedComment {
+			return true
+		}
 	}
+
+	return false
+}
+
+func printParserError(out io.Writer, source string, errors []diagnostic.Diagnostic) {
+	diagnostic.RenderAll(out, source, errors)
 }
@@ -0,0 +1,61 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStartMultilineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let add = fn(a, b) {\n  a + b\n};\nadd(1, 2)\n",
+			">> .. .. >> 3\n>> ",
+		},
+		{
+			"[1,\n2]\n",
+			">> .. [1, 2]\n>> ",
+		},
+		{
+			"\"foo\nbar\"\n",
+			">> .. foo\nbar\n>> ",
+		},
+		{
+			"`foo\n\"bar\"`\n",
+			">> .. foo\n\"bar\"\n>> ",
+		},
+		{
+			"\"say \\\"hi\\\"\"\n",
+			">> say \"hi\"\n>> ",
+		},
+		{
+			"\"a ${1 +\n2} b\"\n",
+			">> .. a 3 b\n>> ",
+		},
+		{
+			"1 + /* a\nb */ 2 // done\n",
+			">> .. 3\n>> ",
+		},
+		{
+			"5 *\n\n",
+			">> .. error[P002]: no prefix parse function for  EOF found\n" +
+				" --> 2:1\n" +
+				"  |\n" +
+				"2 | \n" +
+				"  | ^\n" +
+				"  = hint: the input ended in the middle of an expression\n>> ",
+		},
+	}
+
+	for idx, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("[%d] wrong output. expected=%q, got=%q", idx, tt.expected, out.String())
+		}
+	}
+}
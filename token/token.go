@@ -1,21 +1,67 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position is a location in the source, lines and columns are 1-based
+// while Offset is the 0-based byte offset into the input.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Span covers the source from Start up to, but not including, End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Span    Span
 }
 
 const (
 	ILLEGAL = " ILLEGAL"
-	EOF     = " EOF"
+	// ERROR is a malformed literal, its Literal describes what is wrong
+	ERROR = " ERROR"
+	EOF   = " EOF"
 
 	// Identifiers + literals
 	IDENT  = " IDENT" // add, foobar, x, y, ...
 	INT    = " INT"   // 1343456
+	FLOAT  = " FLOAT" // 3.14, 1e-9
 	STRING = "STRING" // 1343456
 
+	// an interpolated string is split around its ${...} expressions, e.g.
+	// "a ${x} b ${y} c" is STRING_HEAD x STRING_MID y STRING_TAIL
+	STRING_HEAD = " STRING_HEAD"
+	STRING_MID  = " STRING_MID"
+	STRING_TAIL = " STRING_TAIL"
+
+	// DOC_COMMENT is the text of a /// comment
+	DOC_COMMENT = " DOC_COMMENT"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -23,11 +69,24 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 	EQ       = "=="
 	NOT_EQ   = "!="
+	AND      = "&&"
+	OR       = "||"
+	// set intersection and union
+	AMPERSAND = "&"
+	PIPE      = "|"
+	// null coalescing and optional chaining, a?.b and a?[b] are null when a
+	// is null
+	NULLISH          = "??"
+	OPTIONAL_DOT     = "?."
+	OPTIONAL_BRACKET = "?["
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
 
 	// Delimiters
 	COMMA     = ","
@@ -49,16 +108,28 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	NULL     = "NULL"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"if":     IF,
-	"else":   ELSE,
-	"true":   TRUE,
-	"false":  FALSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"if":       IF,
+	"else":     ELSE,
+	"true":     TRUE,
+	"false":    FALSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"null":     NULL,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,34 @@
+package vm
+
+import (
+	"github.com/aryuuu/gonkey-lang/code"
+	"github.com/aryuuu/gonkey-lang/object"
+	"github.com/aryuuu/gonkey-lang/token"
+)
+
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int
+
+	// callback is set for the frames of functions called by builtins, they
+	// are left out of stack traces like in the evaluator
+	callback bool
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		ip:          -1,
+		basePointer: basePointer,
+	}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// Pos returns the source position of the instruction being executed.
+func (f *Frame) Pos() token.Position {
+	return f.cl.Fn.Positions.Lookup(f.ip)
+}
@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+// cell boxes a variable captured by a closure. Once captured, the local slot
+// and the closure's free variable both hold the same cell, so assignments
+// made on either side are visible to the other. Cells never reach Monkey
+// code, every load goes through deref.
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType {
+	return "CELL"
+}
+
+func (c *cell) Inspect() string {
+	return fmt.Sprintf("cell[%p]", c)
+}
+
+// deref returns the value held by obj if it is a cell, obj itself otherwise.
+func deref(obj object.Object) object.Object {
+	if c, ok := obj.(*cell); ok {
+		return c.value
+	}
+
+	return obj
+}
+
+// iterator walks the elements of a value being looped over by a for loop,
+// it only ever lives on the stack while the loop runs.
+type iterator struct {
+	elements []object.Object
+	next     int
+}
+
+func (it *iterator) Type() object.ObjectType {
+	return "ITERATOR"
+}
+
+func (it *iterator) Inspect() string {
+	return fmt.Sprintf("iterator[%p]", it)
+}
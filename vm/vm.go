@@ -0,0 +1,636 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/aryuuu/gonkey-lang/code"
+	"github.com/aryuuu/gonkey-lang/compiler"
+	"github.com/aryuuu/gonkey-lang/evaluator"
+	"github.com/aryuuu/gonkey-lang/object"
+)
+
+const (
+	StackSize   = 1 << 20
+	GlobalsSize = 1 << 16
+	MaxFrames   = 1 << 16
+)
+
+type VM struct {
+	constants   []object.Object
+	globals     []object.Object
+	globalNames []string
+
+	stack []object.Object
+	sp    int // always points to the next free slot, top of stack is stack[sp-1]
+
+	frames []*Frame
+
+	// result is the value of the last expression statement, or the error
+	// that halted the program
+	result object.Object
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Positions:    bytecode.Positions,
+	}
+	mainClosure := &object.Closure{Fn: mainFn}
+
+	return &VM{
+		constants:   bytecode.Constants,
+		globals:     make([]object.Object, GlobalsSize),
+		globalNames: bytecode.GlobalNames,
+		stack:       make([]object.Object, 0, 2048),
+		sp:          0,
+		frames:      []*Frame{NewFrame(mainClosure, 0)},
+	}
+}
+
+// NewWithGlobalsStore creates a vm sharing globals with previous runs, e.g.
+// across several REPL inputs.
+func NewWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = globals
+
+	return vm
+}
+
+// Result returns the value of the last expression statement executed, or the
+// *object.Error that stopped the program. It is nil when no expression
+// statement ran last, mirroring what evaluator.Eval returns.
+func (vm *VM) Result() object.Object {
+	return vm.result
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[len(vm.frames)-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames = append(vm.frames, f)
+}
+
+func (vm *VM) popFrame() *Frame {
+	f := vm.frames[len(vm.frames)-1]
+	vm.frames = vm.frames[:len(vm.frames)-1]
+
+	return f
+}
+
+// errHalt stops the main loop once a Monkey runtime error is stored in result.
+var errHalt = fmt.Errorf("halt")
+
+// Run executes the bytecode. Runtime errors of the program are not returned
+// but reported through Result as *object.Error, like the evaluator does, the
+// returned error is only set for malformed bytecode.
+func (vm *VM) Run() error {
+	err := vm.run(0)
+	if err == errHalt {
+		return nil
+	}
+
+	return err
+}
+
+// run executes instructions until the frame at depth returns, or until the
+// main function ends for a depth of 0.
+func (vm *VM) run(depth int) error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for len(vm.frames) > depth && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip += 1
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		var err error
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err = vm.push(vm.constants[constIndex])
+
+		case code.OpPop:
+			vm.result = vm.pop()
+
+		case code.OpTrue:
+			err = vm.push(evaluator.TRUE)
+
+		case code.OpFalse:
+			err = vm.push(evaluator.FALSE)
+
+		case code.OpNull:
+			err = vm.push(evaluator.NULL)
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod,
+			code.OpUnion, code.OpIntersect,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan,
+			code.OpGreaterEqual, code.OpLessEqual:
+			right := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evaluator.EvalInfix(infixOperators[op], left, right))
+
+		case code.OpBang:
+			err = vm.pushResult(evaluator.EvalPrefix("!", vm.pop()))
+
+		case code.OpMinus:
+			err = vm.pushResult(evaluator.EvalPrefix("-", vm.pop()))
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !evaluator.IsTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			truthy := evaluator.IsTruthy(vm.stack[vm.sp-1])
+			if truthy == (op == code.OpJumpTruthyOrPop) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpJumpNotNullOrPop, code.OpJumpNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			isNull := vm.stack[vm.sp-1] == evaluator.NULL
+			if isNull == (op == code.OpJumpNull) {
+				vm.currentFrame().ip = pos - 1
+			} else if op == code.OpJumpNotNullOrPop {
+				vm.pop()
+			}
+
+		case code.OpIter:
+			elements, iterErr := evaluator.IterableElements(vm.pop())
+			if iterErr != nil {
+				err = vm.halt(iterErr)
+			} else {
+				err = vm.push(&iterator{elements: elements})
+			}
+
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			it := vm.stack[vm.sp-1].(*iterator)
+			if it.next >= len(it.elements) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				err = vm.push(it.elements[it.next])
+				it.next += 1
+			}
+
+		case code.OpSetGlobal, code.OpAssignGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			if op == code.OpAssignGlobal && vm.globals[globalIndex] == nil {
+				err = vm.undeclared(vm.globalNames[globalIndex])
+				break
+			}
+			vm.globals[globalIndex] = vm.pop()
+			nameClosure(vm.globals[globalIndex], vm.globalNames[globalIndex])
+			// a let statement has no value, see Result
+			vm.result = nil
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+			err = vm.pushVariable(vm.globals[globalIndex], vm.globalNames[globalIndex])
+
+		case code.OpSetLocal, code.OpAssignLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			slot := vm.currentFrame().basePointer + int(localIndex)
+			if op == code.OpAssignLocal && deref(vm.stack[slot]) == nil {
+				err = vm.undeclared(vm.currentFrame().cl.Fn.LocalNames[localIndex])
+				break
+			}
+			value := vm.pop()
+			nameClosure(value, vm.currentFrame().cl.Fn.LocalNames[localIndex])
+			if c, ok := vm.stack[slot].(*cell); ok {
+				c.value = value
+			} else {
+				vm.stack[slot] = value
+			}
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			slot := vm.currentFrame().basePointer + int(localIndex)
+			err = vm.pushVariable(deref(vm.stack[slot]), vm.currentFrame().cl.Fn.LocalNames[localIndex])
+
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			builtin, _ := evaluator.LookupBuiltin(evaluator.BuiltinNames()[builtinIndex])
+			err = vm.push(builtin)
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			cl := vm.currentFrame().cl
+			err = vm.pushVariable(deref(cl.Free[freeIndex]), cl.Fn.FreeNames[freeIndex])
+
+		case code.OpSetFree, code.OpAssignFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			cl := vm.currentFrame().cl
+			if op == code.OpAssignFree && deref(cl.Free[freeIndex]) == nil {
+				err = vm.undeclared(cl.Fn.FreeNames[freeIndex])
+				break
+			}
+			value := vm.pop()
+			nameClosure(value, cl.Fn.FreeNames[freeIndex])
+			cl.Free[freeIndex].(*cell).value = value
+
+		case code.OpCaptureLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			slot := vm.currentFrame().basePointer + int(localIndex)
+			c, ok := vm.stack[slot].(*cell)
+			if !ok {
+				c = &cell{value: vm.stack[slot]}
+				vm.stack[slot] = c
+			}
+			err = vm.push(c)
+
+		case code.OpCaptureFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err = vm.push(vm.currentFrame().cl.Free[freeIndex])
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp -= numElements
+
+			err = vm.push(&object.Array{Elements: elements})
+
+		case code.OpInterpolate:
+			numParts := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			parts := vm.stack[vm.sp-numParts : vm.sp]
+			str := evaluator.Interpolate(parts)
+			vm.sp -= numParts
+
+			err = vm.push(str)
+
+		case code.OpMap:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			m := vm.buildMap(vm.sp-numElements, vm.sp)
+			vm.sp -= numElements
+
+			err = vm.pushResult(m)
+
+		case code.OpTuple:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			elements := make([]object.Object, numElements)
+			copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+			vm.sp -= numElements
+
+			err = vm.push(&object.Tuple{Elements: elements})
+
+		case code.OpSet:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			set := evaluator.NewSet(vm.stack[vm.sp-numElements : vm.sp])
+			vm.sp -= numElements
+
+			err = vm.pushResult(set)
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evaluator.EvalIndex(left, index))
+
+		case code.OpSlice:
+			high := vm.pop()
+			low := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evaluator.EvalSlice(left, low, high))
+
+		case code.OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evaluator.EvalIndexAssign(left, index, value))
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+			err = vm.callFunction(int(numArgs))
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+			if len(vm.frames) == 1 {
+				// return at the top level ends the program
+				vm.result = returnValue
+				return errHalt
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			err = vm.push(returnValue)
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			err = vm.push(evaluator.NULL)
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+			err = vm.pushClosure(int(constIndex), int(numFree))
+
+		default:
+			def, lookupErr := code.Lookup(byte(op))
+			if lookupErr != nil {
+				return lookupErr
+			}
+			return fmt.Errorf("unhandled opcode %s", def.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var infixOperators = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpMod:          "%",
+	code.OpUnion:        "|",
+	code.OpIntersect:    "&",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpLessThan:     "<",
+	code.OpGreaterEqual: ">=",
+	code.OpLessEqual:    "<=",
+}
+
+// pushResult pushes the outcome of an operation, halting the vm when it is
+// an error.
+func (vm *VM) pushResult(obj object.Object) error {
+	if errObj, ok := obj.(*object.Error); ok {
+		return vm.halt(errObj)
+	}
+
+	return vm.push(obj)
+}
+
+// nameClosure names a closure after the variable it is bound to, unless it
+// already has a name, the way the evaluator names its functions.
+func nameClosure(obj object.Object, name string) {
+	if cl, ok := obj.(*object.Closure); ok && cl.Name == "" {
+		cl.Name = name
+	}
+}
+
+// pushVariable pushes the value of the variable name, which is nil when its
+// let statement has not run yet.
+func (vm *VM) pushVariable(value object.Object, name string) error {
+	if value == nil {
+		return vm.halt(evaluator.NewError("identifier not found: %s", name))
+	}
+
+	return vm.push(value)
+}
+
+// undeclared fails an assignment to a variable that has not been defined.
+func (vm *VM) undeclared(name string) error {
+	return vm.halt(evaluator.NewError("assignment to undeclared identifier: %s", name))
+}
+
+// halt stops the program with a runtime error. An error raised by the vm
+// gets the position of the current instruction and the calls leading to it,
+// one coming back from a builtin's callback already has them.
+func (vm *VM) halt(err *object.Error) error {
+	if !err.Pos.IsValid() {
+		err.Pos = vm.currentFrame().Pos()
+		err.Stack = vm.stackTrace()
+	}
+
+	vm.result = err
+	return errHalt
+}
+
+// stackTrace lists the calls of the frames being run, innermost first.
+func (vm *VM) stackTrace() []object.Frame {
+	var stack []object.Frame
+	for i := len(vm.frames) - 1; i > 0; i-- {
+		if vm.frames[i].callback {
+			continue
+		}
+
+		stack = append(stack, object.Frame{
+			Function: vm.frames[i].cl.Name,
+			Pos:      vm.frames[i-1].Pos(),
+		})
+	}
+
+	return stack
+}
+
+func (vm *VM) buildMap(startIndex, endIndex int) object.Object {
+	m := object.NewMap()
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := object.HashKeyOf(key)
+		if !ok {
+			return evaluator.NewError("unusable as hash key: %s", key.Type())
+		}
+
+		m.Set(hashKey, object.HashPair{
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	return m
+}
+
+func (vm *VM) callFunction(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs, false)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return vm.halt(evaluator.NewError("not a function: %s", callee.Type()))
+	}
+}
+
+// callClosure enters cl, callback tells whether a builtin is calling it.
+func (vm *VM) callClosure(cl *object.Closure, numArgs int, callback bool) error {
+	if numArgs != cl.Fn.NumParameters {
+		err := evaluator.NewError("wrong number of arguments. got=%d, want=%d", numArgs, cl.Fn.NumParameters)
+		err.Pos = vm.currentFrame().Pos()
+		err.Stack = vm.stackTrace()
+		if !callback {
+			// the evaluator reports the call that failed as well
+			err.Stack = append([]object.Frame{{Function: cl.Name, Pos: err.Pos}}, err.Stack...)
+		}
+
+		return vm.halt(err)
+	}
+
+	if len(vm.frames) >= MaxFrames {
+		return vm.halt(evaluator.NewError("stack overflow"))
+	}
+
+	frame := NewFrame(cl, vm.sp-numArgs)
+	frame.callback = callback
+	vm.pushFrame(frame)
+
+	if err := vm.grow(frame.basePointer + cl.Fn.NumLocals); err != nil {
+		return err
+	}
+
+	// clear the remaining locals, a cell left behind by an earlier call
+	// would otherwise be shared with this one
+	for i := vm.sp; i < frame.basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+
+	result := builtin.Fn(vm.call, args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if result == nil {
+		return vm.push(evaluator.NULL)
+	}
+
+	return vm.pushResult(result)
+}
+
+// call runs fn to completion on top of the current stack and returns its
+// result, it is the Caller builtins use to call back into the program.
+func (vm *VM) call(fn object.Object, args []object.Object) object.Object {
+	if builtin, ok := fn.(*object.Builtin); ok {
+		return builtin.Fn(vm.call, args...)
+	}
+
+	cl, ok := fn.(*object.Closure)
+	if !ok {
+		return evaluator.NewError("not a function: %s", fn.Type())
+	}
+
+	sp := vm.sp
+	depth := len(vm.frames)
+
+	err := vm.push(cl)
+	for _, arg := range args {
+		if err == nil {
+			err = vm.push(arg)
+		}
+	}
+
+	if err == nil {
+		err = vm.callClosure(cl, len(args), true)
+	}
+	if err == nil {
+		err = vm.run(depth)
+	}
+
+	// a runtime error leaves the frames of the callback behind
+	vm.frames = vm.frames[:depth]
+	vm.sp = sp
+
+	switch {
+	case err == errHalt:
+		return vm.result
+	case err != nil:
+		return evaluator.NewError("%s", err)
+	}
+
+	return vm.stack[sp]
+}
+
+func (vm *VM) pushClosure(constIndex int, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: function, Free: free, Name: function.Name})
+}
+
+// grow makes sure the stack has room for size slots.
+func (vm *VM) grow(size int) error {
+	if size > StackSize {
+		return vm.halt(evaluator.NewError("stack overflow"))
+	}
+
+	for len(vm.stack) < size {
+		vm.stack = append(vm.stack, nil)
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if err := vm.grow(vm.sp + 1); err != nil {
+		return err
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp += 1
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp -= 1
+
+	return o
+}
@@ -0,0 +1,455 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/aryuuu/gonkey-lang/ast"
+	"github.com/aryuuu/gonkey-lang/compiler"
+	"github.com/aryuuu/gonkey-lang/evaluator"
+	"github.com/aryuuu/gonkey-lang/lexer"
+	"github.com/aryuuu/gonkey-lang/object"
+	"github.com/aryuuu/gonkey-lang/parser"
+)
+
+// TestEquivalence runs every input through both the evaluator and the vm and
+// expects the same result.
+func TestEquivalence(t *testing.T) {
+	inputs := []string{
+		"1",
+		"1 + 2 * 3 - 4 / 2",
+		"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+		"-5 + 10",
+		"1 < 2",
+		"1 > 2",
+		"1 == 1",
+		"1 != 1",
+		"true == false",
+		"(1 < 2) == true",
+		"!true",
+		"!!5",
+		`"mon" + "key"`,
+		"if (true) { 10 }",
+		"if (false) { 10 }",
+		"if (1 > 2) { 10 } else { 20 }",
+		"if (10 > 1) { if (10 > 1) { return 10; } return 1; }",
+		"return 10; 9;",
+		"9; return 2 * 5; 9;",
+		"let a = 5; let b = a * 2; a + b",
+		"[1, 2 * 2, 3 + 3]",
+		"[1, 2, 3][1 + 1]",
+		"[1, 2, 3][3]",
+		"[1, 2, 3][-1]",
+		`{"foo": 5}["foo"]`,
+		`{"foo": 5}["bar"]`,
+		`{1 + 1: "two"}[2]`,
+		"let identity = fn(x) { x; }; identity(5);",
+		"let add = fn(x, y) { return x + y; }; add(5 + 5, add(5, 5));",
+		"fn(x) { x * 2 }(4)",
+		"let apply = fn(f, x) { f(x) }; apply(fn(y) { y + 1 }, 1)",
+		`len("hello")`,
+		"len([1, 2, 3])",
+		"first([1, 2, 3])",
+		"last([1, 2, 3])",
+		"rest([1, 2, 3])",
+		"push([1, 2], 3)",
+		"5 + true",
+		"5 + true; 5;",
+		"-true",
+		"true + false",
+		`"Hello" - "World"`,
+		"if (10 > 1) { true + false; }",
+		`{"name": "Monkey"}[fn(x) { x }]`,
+		`{fn(x) { x }: 1}`,
+		"len(1)",
+		`len("one", "two")`,
+		"1(2)",
+		"let f = fn(x) { x + true }; f(1)",
+		"fn(a, b) { a + b }(1)",
+		"let newAdder = fn(x) { fn(y) { x + y } }; newAdder(2)(3)",
+		"let x = 1; let f = fn() { let x = 2; x }; f() * 10 + x",
+		"let x = 1; let f = fn() { x }; let g = fn() { let x = 2; f() }; g()",
+		"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10)",
+		"let x = 1; x = x + 1; x",
+		"let x = 1; x = 5",
+		"let x = 1; let y = 2; x = y = 3; x + y",
+		"let x = 1; let f = fn() { x = 10 }; f(); x",
+		"let x = 1; let f = fn() { let x = 5; x = 10 }; f(); x",
+		"let f = fn(n) { n = n * 2; n }; f(4)",
+		"let newCounter = fn() { let count = 0; fn() { count = count + 1; count } }; let c = newCounter(); c(); c(); c()",
+		"let pair = fn() { let n = 0; [fn() { n = n + 1 }, fn() { n }] }; let p = pair(); p[0](); p[0](); p[1]()",
+		"let f = fn() { let n = 0; fn() { fn() { n = n + 10; n } } }; let g = f()(); g(); g()",
+		"let f = fn() { let n = 1; let g = fn() { n }; n = 2; g() }; f()",
+		"let w = fn() { let down = fn(x) { if (x == 0) { 0 } else { down(x - 1) } }; down(3) }; w()",
+		"let a = [1, 2, 3]; let b = a; b[0] = 9; a",
+		`let m = {"a": 1}; m["a"] = 2; m["b"] = 3; [m["a"], m["b"], len(m)]`,
+		"let a = [1]; a[1] = 2",
+		`let s = "ab"; s[0] = "c"`,
+		"let m = {}; m[fn(x) { x }] = 1",
+		"let i = 0; while (i < 10) { i = i + 1; } i",
+		"let i = 0; while (true) { i = i + 1; if (i == 5) { break; } } i",
+		"let i = 0; let s = 0; while (i < 10) { i = i + 1; if (i == 2 * (i / 2)) { continue; } s = s + i; } s",
+		"let i = 0; let c = 0; while (i < 3) { let j = 0; while (true) { if (j == 4) { break; } j = j + 1; c = c + 1; } i = i + 1; } c",
+		"let find = fn(limit) { let i = 0; while (true) { if (i * i > limit) { return i; } i = i + 1; } }; find(50)",
+		"let s = 0; for (x in [1, 2, 3]) { s = s + x; } s",
+		`let s = ""; for (c in "abc") { s = c + s; } s`,
+		`let n = 0; for (k in {"a": 1, "b": 2}) { n = n + {"a": 1, "b": 2}[k]; } n`,
+		"for (x in [1, 2, 3]) { } x",
+		"let s = 0; for (x in [1, 2, 3, 4, 5, 6]) { if (x == 2) { continue; } if (x == 5) { break; } s = s + x; } s",
+		"let p = 0; for (x in [1, 2, 3]) { for (y in [1, 2, 3]) { if (y > x) { break; } p = p + 1; } } p",
+		"let r = []; for (x in [1, 2, 3, 4]) { if (x == 1) { continue } else { if (x == 3) { break } } r = push(r, x) } r",
+		"let r = []; for (x in [1, 2]) { r = push(r, x + if (x == 1) { 10 } else { 0 }) } r",
+		"let f = fn(xs) { let s = 0; for (x in xs) { s = s + x; } s }; f([4, 5])",
+		"let f = fn(xs, v) { for (x in xs) { if (x == v) { return true; } } false }; f([1, 2, 3], 2)",
+		"let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }); } fs[0]()",
+		"let f = fn() { let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }); } fs }; f()[0]()",
+		"for (x in [1]) { x }",
+		"while (false) { }",
+		"let f = fn() { while (false) { } }; f()",
+		"if (true) { for (x in [1]) { } }",
+		"for (x in 5) { }",
+		"while (1 + true) { }",
+		"3.14",
+		"1 + 2.5 * 2",
+		"10 / 4.0 - 1",
+		"-1.5 < 1",
+		"2 == 2.0",
+		"int(-3.9) + float(2)",
+		"{2: 1}[2.0]",
+		"[0.5, 1e-9, 3.0]",
+		"1.5 + true",
+		`float("x")`,
+		"9223372036854775807 + 1",
+		"4294967296 * 4294967296",
+		"99999999999999999999 - 99999999999999999998",
+		"-(-9223372036854775807 - 1)",
+		"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(30)",
+		"{99999999999999999999: 1}[99999999999999999999]",
+		"99999999999999999999 > 1.5",
+		"[-7 / 2, -7 % 2, 7 % -2, 7 / -2]",
+		"-99999999999999999999 / 7",
+		"-99999999999999999999 % 7",
+		"-7.5 % 2",
+		"1 / 0",
+		"1 % 0",
+		"99999999999999999999 / 0",
+		"1.5 / 0",
+		"let f = fn(x) { 10 / x }; f(0) + 1",
+		"[true && false, false || true, 1 && 2, false || 5, 5 || false, false && 5]",
+		"if (false) { 1 } || 7",
+		"false && 1 + true",
+		"true && 1 + true",
+		"let n = 0; false && (n = 1); true || (n = 2); n",
+		"let n = 0; let f = fn() { n = n + 1; true }; f() || f(); f() && f(); n",
+		"let f = fn(x) { x > 0 && x < 10 || x == 42 }; [f(5), f(42), f(11)]",
+		"let i = 0; while (i < 10 && i != 4) { i = i + 1; } i",
+		"[1 <= 2, 2 >= 3, 1.5 >= 1, 99999999999999999999 <= 1]",
+		`["a" == "a", "a" != "b", "a" < "b", "ab" >= "b", "B" <= "a"]`,
+		`[[1, [2]] == [1, [2]], [1] == [2], {"a": [1]} == {"a": [1]}, {"a": 1} != {"a": 2}]`,
+		"let f = fn(x) { x }; [[f] == [f], [1] == 1]",
+		"let a = [1]; let b = [1]; a[0] = a; b[0] = b; a == b",
+		`let len = len("ab"); len`,
+		"let x = 5; let f = fn() { let x = x * 2; x }; f()",
+		"let y = y",
+		"let f = fn() { g() }; let g = fn() { 1 }; f()",
+		"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; [even(10), odd(7), even(3)]",
+		"let f = fn() { g() }; f(); let g = fn() { 1 };",
+		"let f = fn() { let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } }; [even(4), odd(3), even(3)] }; f()",
+		"let f = fn() { let h = fn() { k() }; let k = fn() { 2 }; h() }; f()",
+		"let f = fn() { let h = fn() { k() }; h(); let k = fn() { 2 }; }; f()",
+		"let f = fn() { let h = fn() { n }; let n = 1; let g = fn() { n = n + 1; h() }; g() }; f()",
+		"let f = fn() { let h = fn() { i }; for (i in [1, 2]) { } h() }; f()",
+		"let f = fn() { let g = fn() { fn() { k } }; let k = 3; g()() }; [f(), k]",
+		"let f = fn() { x = 1 }; let x = 0; f(); x",
+		"let a = [0]; a[0] = 1; x = a[0]; let x = 2",
+		"let f = fn() { y = 1 }; f()",
+		"let f = fn() { x = 1; let x = 2; x }; f()",
+		"let f = fn() { let g = fn() { n = 5 }; let n = 1; g(); n }; f()",
+		"let f = fn() { let g = fn() { n = 5 }; g(); let n = 1; n }; f()",
+		"if (false) { let q = 1 }; q",
+		"if (true) { let q = 1 }; q",
+		"let f = fn() { if (false) { let q = 1 }; q }; f()",
+		"let f = fn() { if (false) { let q = 1 }; fn() { q } }; f()()",
+		"undefined + 1",
+		"let inner = fn(x) {\n\tx + true\n};\nlet outer = fn(f, y) { f(y) };\nouter(inner, 1);",
+		"let f = fn() { len(1) }; let g = fn() { [f()] }; g()",
+		"let g = fn(x) { h(x) }; let h = fn(y) { y / 0 }; let f = fn() { map([1], g) }; f()",
+		"map([1], fn(a, b) { a })",
+		"let h = fn(x) { x + 1 }; [h, fn() { if (true) { 1 } else { [2] } }]",
+		"let mk = fn() { fn(x) { x / 0 } }; let h = mk(); h(1)",
+		"let mk = fn() { fn(x) { x / 0 } }; let f = fn() { let g = mk(); g(1) }; f()",
+		"let h = 0; h = fn(x) { x / 0 }; h(1)",
+		"let mk = fn() { fn(x) { x / 0 } }; for (each in [mk()]) { each(1) }",
+		"let f = fn() { let g = 0; let set = fn() { g = fn() { 1 / 0 } }; set(); g() }; f()",
+		"let f = fn(x) { f(x) + 1 }; let g = fn() { f(1, 2) }; g()",
+		`let m = {"a": 1}; m[[1]] = 2`,
+		"for (x in 1) { x }",
+		`"a" <= 1`,
+		"[1] >= [1]",
+		`let i = 0; let s = ""; while (s <= "aaa") { s = s + "a"; i = i + 1; } i`,
+		`let name = "Monkey"; let items = [1, "a"]; "hello ${name}, ${len(items)} items: ${items} ${"n${1 + 1}"}"`,
+		`let f = fn(x) { "<${x}>" }; f(f(1.5))`,
+		`"a ${1 + true} b"`,
+		`let café = "naïve ☺"; [len(café), byte_len(café), café[2], café[6], café[7], bytes("é")]`,
+		`let s = ""; for (c in "añb") { s = c + s; } s`,
+		`"日本"[0] + "${len("日本")}"`,
+		"/// doc\nlet x = 10; // ten\n/* halve */ x / 2",
+		`join(split(upper(trim("  a,b ")), ","), "-")`,
+		`[contains("monkey", "key"), starts_with("a", "b"), ends_with("ab", "b"), index_of("日本語", "語")]`,
+		`[replace("banana", "a", "o"), substr("日本語abc", 1, 3), repeat("ab", 2), format("{} is {}", "x", [1])]`,
+		`format("{} {}", 1)`,
+		`substr("abc", -1)`,
+		`"tab\there \"quoted\" \u{263A}" + ` + "`raw\\n`",
+		"let k = 10; map([1, 2, 3], fn(x) { x * k })",
+		`map(["a", "bc"], len)`,
+		"filter(range(10), fn(x) { x % 3 == 0 })",
+		"reduce(range(1, 5), fn(acc, x) { acc * x })",
+		"reduce([1, 2], fn(acc, x) { push(acc, x) }, [])",
+		"reduce([], fn(acc, x) { acc })",
+		`[sort([3, 1.5, 2]), sort_by(["ccc", "a", "bb"], len), sort([1, "a"])]`,
+		"[any([1, 2], fn(x) { x > 1 }), all([1, 2], fn(x) { x > 1 })]",
+		`[zip([1, 2, 3], ["a", "b"]), range(10, 0, -3), reverse("héllo"), flatten([[1], 2]), unique([1, 1.0, [1], [1]])]`,
+		"map(range(3), fn(i) { map(range(i), fn(j) { i * j }) })",
+		"let f = fn() { map([1, 2], fn(x) { return x * 3; }) }; f()",
+		"let f = fn(n) { if (n == 0) { 0 } else { reduce(range(n), fn(acc, x) { acc + f(n - 1) }, 0) + 1 } }; f(3)",
+		`let r = map([1, "a"], fn(x) { x + 1 }); 5`,
+		"let g = fn(x) { -true }; let xs = filter([1], g); 1",
+		"range(0, 1, 0)",
+		"map([1], fn(x, y) { x })",
+		`{"b": 1, "a": 2, 3: [1]}`,
+		`let m = {"b": 1, "a": 2}; m["c"] = 3; m["b"] = 4; [m, keys(m), values(m), entries(m), len(m)]`,
+		`let m = {"a": 1, "b": 2}; [has(m, "a"), has(m, "z"), delete(m, "a"), m, merge(m, {"b": 3, "c": 4})]`,
+		`let out = []; for (k in {"z": 1, "y": 2, "x": 3}) { out = push(out, k) }; out`,
+		`has({}, [1])`,
+		"[[1, 2, 3, 4][1:3], [1, 2, 3][:2], [1, 2, 3][-2:], [1, 2, 3][:], [1, 2, 3][3:1]]",
+		`["hello"[1:3], "日本語abc"[-4:-1], "abc"[-1], [1, 2, 3][-3], [1][-2]]`,
+		"let a = [1, 2, 3]; a[-1] = 9; let b = a[:]; b[0] = 0; [a, b]",
+		`[1, 2]["a":]`,
+		`{"a": 1}[:1]`,
+		"let f = fn(xs) { xs[1:] }; f(f([1, 2, 3]))",
+		`let t = (1, "a", (2, 3)); [t, (1,), (), t[-1], t[1:], len(t), t == (1, "a", (2, 3))]`,
+		"[{3, 1, 2, 1}, {1, 2} | {2, 3}, {1, 2, 3} & {3, 2}, {1, 2, 3} - {2}, {1} & {2}, {1, 2} == {2, 1}]",
+		`[set("abca"), tuple({2, 1}), has({1, 2}, 2), len({1, 1})]`,
+		`let m = {(1, 2): "point", {1, 2}: "set"}; [m[(1, 2)], m[{2, 1}], m]`,
+		"let out = []; for (x in {3, 1}) { out = push(out, x) }; for (x in (4, 5)) { out = push(out, x) }; out",
+		"{1, [2]}",
+		"{1} | [2]",
+		"let t = (1, 2); t[0] = 3;",
+		"[null, null == null, null ?? 1, 0 ?? 1, false ?? 1, null ?? null ?? 3]",
+		"let n = 0; let f = fn() { n = n + 1 }; 5 ?? f(); null ?? f(); n",
+		`let m = {"a": {"b": [1, 2]}, "c": null}; [m?.a?.b?[1], m?.c?.d, m?.a?.b?[1:], m?.z ?? "none"]`,
+		"let x = null; let boom = fn() { 1 / 0 }; [x?[boom()], x?[boom():], x?.a?.b]",
+		"let f = fn(x) { x?.name ?? \"anonymous\" }; [f(null), f({}), f({\"name\": \"ann\"})]",
+		"let x = null; x?.a[0]",
+		`[null?["a"]["b"], null?[0][1:], null?.f(1 / 0), null?.a[0] ?? "none"]`,
+		`let m = {"a": null, "f": fn(x) { x * 2 }}; [m?.a?[0]["b"], m?.f(2), m?.a?.b(1)[2]]`,
+		`let m = {"a": null}; m?.a[0]`,
+		"let m = null; (m?[0])[1]",
+		"let m = null; [(m?[0])?[1], (m?.a)?.b]",
+		"let a = [[1, 2]]; (a[0])[1] = 3; a",
+		"let f = fn() { [fn(x) { x / 0 }] }; (f()[0])(5)",
+		"let f = fn() {}; [f() ?? 2, f() == null, [f()], f()]",
+		`"${if (true) { let x = 1 }}"`,
+		"let a = 0; a = if (false) { 1 } else { let z = 2 }; a == null",
+		"let a = 0; a = if (false) { 1 } else { let z = 2 }; a + 1",
+		`join([if (true) { let q = 1 }, if (true) {}], ",")`,
+		"if (true) { let x = 1 }",
+		"if (true) { 1; let x = 2 }",
+		"let f = fn() { let x = 1; }; let g = fn() { if (false) { 1 } }; [f(), g()]",
+		"1?[0]",
+	}
+
+	for _, input := range inputs {
+		program := parse(t, input)
+
+		expected := evaluator.Eval(program, object.NewEnvironment())
+		actual := run(t, program)
+
+		testSameObject(t, input, expected, actual)
+	}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input: `
+			let fibonacci = fn(x) {
+				if (x < 2) { return x; }
+				fibonacci(x - 1) + fibonacci(x - 2)
+			};
+			fibonacci(15);`,
+			expected: 610,
+		},
+		{
+			input: `
+			let wrapper = fn() {
+				let countDown = fn(x) {
+					if (x == 0) { return 0; }
+					countDown(x - 1);
+				};
+				countDown(1);
+			};
+			wrapper();`,
+			expected: 0,
+		},
+		{
+			input: `
+			let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } };
+			sum(5000);`,
+			expected: 12502500,
+		},
+	}
+
+	for _, tc := range testCases {
+		result := run(t, parse(t, tc.input))
+		testIntegerObject(t, tc.input, result, tc.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			input: `
+			let newAdder = fn(a, b) { fn(c) { a + b + c } };
+			let adder = newAdder(1, 2);
+			adder(8);`,
+			expected: 11,
+		},
+		{
+			input: `
+			let newAdderOuter = fn(a, b) {
+				let c = a + b;
+				fn(d) {
+					let e = d + c;
+					fn(f) { e + f; };
+				};
+			};
+			let newAdderInner = newAdderOuter(1, 2);
+			let adder = newAdderInner(3);
+			adder(8);`,
+			expected: 14,
+		},
+		{
+			input: `
+			let global = 10;
+			let f = fn() { let local = 5; fn() { global + local } };
+			f()();`,
+			expected: 15,
+		},
+	}
+
+	for _, tc := range testCases {
+		result := run(t, parse(t, tc.input))
+		testIntegerObject(t, tc.input, result, tc.expected)
+	}
+}
+
+func TestCallingWithWrongArguments(t *testing.T) {
+	input := "fn(a, b) { a + b }(1)"
+
+	result := run(t, parse(t, input))
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got=%T(%+v)", result, result)
+	}
+
+	expected := "wrong number of arguments. got=1, want=2"
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+	}
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	input := `
+	let fibonacci = fn(x) {
+		if (x < 2) { return x; }
+		fibonacci(x - 1) + fibonacci(x - 2)
+	};
+	fibonacci(20);`
+
+	l := lexer.New("", input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		b.Fatalf("compiler error: %s", err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			b.Fatalf("vm error: %s", err)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	l := lexer.New("", input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.GetErrors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.GetErrors())
+	}
+
+	return program
+}
+
+func run(t *testing.T, program *ast.Program) object.Object {
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	return vm.Result()
+}
+
+func testSameObject(t *testing.T, input string, expected, actual object.Object) {
+	if expected == nil || actual == nil {
+		if expected != actual {
+			t.Errorf("%q: expected=%v, got=%v", input, expected, actual)
+		}
+		return
+	}
+
+	if expected.Type() != actual.Type() {
+		t.Errorf("%q: wrong type. expected=%s (%s), got=%s (%s)", input, expected.Type(), expected.Inspect(), actual.Type(), actual.Inspect())
+		return
+	}
+
+	if expected.Inspect() != actual.Inspect() {
+		t.Errorf("%q: wrong value. expected=%s, got=%s", input, expected.Inspect(), actual.Inspect())
+		return
+	}
+
+	// errors are also raised at the same place, through the same calls
+	if expectedErr, ok := expected.(*object.Error); ok {
+		actualErr := actual.(*object.Error)
+		if expectedErr.Trace() != actualErr.Trace() {
+			t.Errorf("%q: wrong trace. expected=\n%s\ngot=\n%s", input, expectedErr.Trace(), actualErr.Trace())
+		}
+	}
+}
+
+func testIntegerObject(t *testing.T, input string, obj object.Object, expected int64) {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("%q: obj should be of type object.Integer, got=%T (%+v)", input, obj, obj)
+		return
+	}
+
+	if result.Value != expected {
+		t.Errorf("%q: obj value should be %d, got=%d", input, expected, result.Value)
+	}
+}